@@ -0,0 +1,328 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// accessoryManifest describes all accessories that a gopher can wear. The
+// default manifest is embedded as rsc/accessories.json and players can replace
+// it with their own, see loadAccessoryManifest.
+type accessoryManifest struct {
+	Groups      []accessoryGroup `json:"groups"`
+	Accessories []accessory      `json:"accessories"`
+}
+
+// accessoryGroup contains accessories that cannot be worn together, e.g. two
+// pairs of glasses. A gopher wears at most one accessory from each group.
+type accessoryGroup struct {
+	Name string `json:"name"`
+	// Title is shown in the wardrobe. Titles can hold translations of it,
+	// keyed by language id.
+	Title  string            `json:"title"`
+	Titles map[string]string `json:"titles"`
+	// NoneWeight is the weight for wearing nothing from this group, compared to
+	// the weights of the accessories in the group.
+	NoneWeight float64 `json:"noneWeight"`
+}
+
+type accessory struct {
+	// Name identifies the accessory in the kill history and the settings.
+	Name string `json:"name"`
+	// Title is shown to the player. Titles can hold translations of it, keyed
+	// by language id.
+	Title  string            `json:"title"`
+	Titles map[string]string `json:"titles"`
+	Group  string            `json:"group"`
+	// Image is drawn on top of the gopher image, with the same size.
+	Image string `json:"image"`
+	// Order defines which accessories are drawn first, lower orders are drawn
+	// below higher orders.
+	Order int `json:"order"`
+	// OffsetX and OffsetY move the image relative to the gopher, in pixels.
+	OffsetX int `json:"offsetX"`
+	OffsetY int `json:"offsetY"`
+	// Weight makes the accessory more or less likely compared to the others
+	// in its group.
+	Weight float64         `json:"weight"`
+	Unlock unlockCondition `json:"unlock"`
+}
+
+// unlockCondition has to be met before an accessory is worn. All non-zero
+// requirements must be met.
+type unlockCondition struct {
+	// Pipes is the number of pipes that a single gopher has to clear.
+	Pipes int `json:"pipes"`
+	// TotalPipes is the number of pipes cleared by all gophers together.
+	TotalPipes int `json:"totalPipes"`
+	// Kills is the number of gophers that have died.
+	Kills int `json:"kills"`
+}
+
+// playerStats are what unlock conditions are checked against.
+type playerStats struct {
+	bestPipes  int
+	totalPipes int
+	kills      int
+}
+
+func statsOf(kills []kill) playerStats {
+	var s playerStats
+	for _, k := range kills {
+		s.bestPipes = max(s.bestPipes, k.Score)
+		s.totalPipes += k.Score
+	}
+	s.kills = len(kills)
+	return s
+}
+
+func (u unlockCondition) metBy(s playerStats) bool {
+	return s.bestPipes >= u.Pipes &&
+		s.totalPipes >= u.TotalPipes &&
+		s.kills >= u.Kills
+}
+
+func (a *accessory) title() string {
+	return localizedTitle(a.Title, a.Titles)
+}
+
+func (g *accessoryGroup) title() string {
+	if g.Title == "" {
+		return g.Name
+	}
+	return localizedTitle(g.Title, g.Titles)
+}
+
+// localizedTitle returns the translation for the current language if there is
+// one that our font can draw.
+func localizedTitle(title string, titles map[string]string) string {
+	if t, ok := titles[currentLanguage.id]; ok && canDraw(t) {
+		return t
+	}
+	return title
+}
+
+// String describes what the player has to do to meet the condition.
+func (u unlockCondition) String() string {
+	var parts []string
+	if u.Pipes > 0 {
+		parts = append(parts, trn(msgUnlockPipes, u.Pipes, u.Pipes))
+	}
+	if u.TotalPipes > 0 {
+		parts = append(parts, trn(msgUnlockTotalPipes, u.TotalPipes, u.TotalPipes))
+	}
+	if u.Kills > 0 {
+		parts = append(parts, trn(msgUnlockKills, u.Kills, u.Kills))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func parseAccessoryManifest(data []byte) (*accessoryManifest, error) {
+	var m accessoryManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+
+	// Names are stored in the kill history and the settings, separated by
+	// spaces.
+	validName := func(name string) bool {
+		return name != "" && name != wearNothing && !strings.ContainsAny(name, " \t\r\n=")
+	}
+
+	groups := make(map[string]bool)
+	for _, g := range m.Groups {
+		if !validName(g.Name) {
+			return nil, fmt.Errorf("accessory group %q has an invalid name", g.Name)
+		}
+		if g.NoneWeight < 0 || math.IsInf(g.NoneWeight, 0) {
+			return nil, fmt.Errorf("accessory group %q has an invalid none weight", g.Name)
+		}
+		groups[g.Name] = true
+	}
+
+	names := make(map[string]bool)
+	for _, a := range m.Accessories {
+		if !validName(a.Name) {
+			return nil, fmt.Errorf("accessory %q has an invalid name", a.Name)
+		}
+		if names[a.Name] {
+			return nil, fmt.Errorf("accessory %q is declared twice", a.Name)
+		}
+		names[a.Name] = true
+		if !groups[a.Group] {
+			return nil, fmt.Errorf("accessory %q has unknown group %q", a.Name, a.Group)
+		}
+		if a.Image == "" {
+			return nil, fmt.Errorf("accessory %q has no image", a.Name)
+		}
+		if a.Weight < 0 || math.IsInf(a.Weight, 0) {
+			return nil, fmt.Errorf("accessory %q has an invalid weight", a.Name)
+		}
+	}
+
+	slices.SortStableFunc(m.Accessories, func(a, b accessory) int {
+		return a.Order - b.Order
+	})
+
+	return &m, nil
+}
+
+// defaultAccessoryManifest is embedded in the executable and must be valid.
+// Asset packs can replace it, they are validated when they are loaded.
+func defaultAccessoryManifest() *accessoryManifest {
+	data, err := fs.ReadFile(assets, "rsc/accessories.json")
+	if err != nil {
+		panic(err)
+	}
+	m, err := parseAccessoryManifest(data)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// loadAccessoryManifest uses the player's own manifest if there is a valid one
+// and the default manifest otherwise. If the player's manifest cannot be used,
+// it also returns the reason.
+func loadAccessoryManifest() (*accessoryManifest, error) {
+	data := loadUserAccessories()
+	if data == nil {
+		return defaultAccessoryManifest(), nil
+	}
+	m, err := parseAccessoryManifest(data)
+	if err == nil {
+		err = m.validateImages(assets)
+	}
+	if err != nil {
+		return defaultAccessoryManifest(), fmt.Errorf("%s: %w", userAccessoriesName, err)
+	}
+	return m, nil
+}
+
+// validateImages checks that all accessory images exist in the given files and
+// have the same size as the gopher.
+func (m *accessoryManifest) validateImages(files fs.FS) error {
+	gopherW, gopherH, err := imageFileSize(files, "rsc/arms_center.png")
+	if err != nil {
+		return err
+	}
+	for _, a := range m.Accessories {
+		w, h, err := imageFileSize(files, a.Image)
+		if err != nil {
+			return fmt.Errorf("image of accessory %q: %w", a.Name, err)
+		}
+		if w != gopherW || h != gopherH {
+			return fmt.Errorf("%s is %dx%d pixels but must be %dx%d like the gopher", a.Image, w, h, gopherW, gopherH)
+		}
+	}
+	return nil
+}
+
+// replaceImage makes all accessories that use the old image use the new one.
+func (m *accessoryManifest) replaceImage(old, new string) {
+	for i := range m.Accessories {
+		if m.Accessories[i].Image == old {
+			m.Accessories[i].Image = new
+		}
+	}
+}
+
+func (m *accessoryManifest) find(name string) (*accessory, bool) {
+	for i := range m.Accessories {
+		if m.Accessories[i].Name == name {
+			return &m.Accessories[i], true
+		}
+	}
+	return nil, false
+}
+
+// layers returns the accessories with the given names in drawing order. Names
+// that are not in the manifest, e.g. from an older kill history, are drawn last
+// from the image "rsc/<name>.png", which is what older versions of the game
+// did. If there is no such image, we draw the placeholderImage.
+func (m *accessoryManifest) layers(names []string) []accessory {
+	var layers []accessory
+	for _, a := range m.Accessories {
+		if slices.Contains(names, a.Name) {
+			layers = append(layers, a)
+		}
+	}
+	for _, name := range names {
+		if _, ok := m.find(name); !ok {
+			image := "rsc/" + name + ".png"
+			if _, err := fs.Stat(assets, image); err != nil {
+				image = placeholderImage
+			}
+			layers = append(layers, accessory{Name: name, Image: image})
+		}
+	}
+	return layers
+}
+
+// inGroup returns the accessories of the given group which are unlocked or,
+// if unlocked is false, which are still locked.
+func (m *accessoryManifest) inGroup(group string, stats playerStats, unlocked bool) []accessory {
+	var list []accessory
+	for _, a := range m.Accessories {
+		if a.Group == group && a.Unlock.metBy(stats) == unlocked {
+			list = append(list, a)
+		}
+	}
+	return list
+}
+
+// roll picks accessories, at most one from each group. The wardrobe maps group
+// names to what the player chose for that group, see settings.wardrobe. Groups
+// without a valid choice get a random accessory. Only unlocked accessories are
+// picked.
+func (m *accessoryManifest) roll(stats playerStats, wardrobe map[string]string) []string {
+	var names []string
+	for _, g := range m.Groups {
+		if choice, ok := wardrobe[g.Name]; ok {
+			if choice == wearNothing {
+				continue
+			}
+			if a, ok := m.find(choice); ok && a.Group == g.Name && a.Unlock.metBy(stats) {
+				names = append(names, a.Name)
+				continue
+			}
+		}
+
+		var candidates []*accessory
+		total := g.NoneWeight
+		for i := range m.Accessories {
+			a := &m.Accessories[i]
+			if a.Group == g.Name && a.Unlock.metBy(stats) {
+				candidates = append(candidates, a)
+				total += a.Weight
+			}
+		}
+
+		r := rand.Float64() * total
+		for _, a := range candidates {
+			if r < a.Weight {
+				names = append(names, a.Name)
+				break
+			}
+			r -= a.Weight
+		}
+	}
+	return names
+}
+
+// newlyUnlocked returns the accessories that are unlocked with the after stats
+// but not with the before stats.
+func (m *accessoryManifest) newlyUnlocked(before, after playerStats) []accessory {
+	var unlocked []accessory
+	for _, a := range m.Accessories {
+		if !a.Unlock.metBy(before) && a.Unlock.metBy(after) {
+			unlocked = append(unlocked, a)
+		}
+	}
+	return unlocked
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/png"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+)
+
+// assets are the files that the game loads its images, sounds and texts from.
+// They are the embedded resources, possibly overlaid by an asset pack, see
+// useAssetPack.
+var assets fs.FS = rsc
+
+// currentPack is the asset pack that is in use, it is nil if there is none.
+// currentPackPath is the path it was loaded from and currentPackCloser closes
+// its files, if they need closing.
+var (
+	currentPack       *assetPack
+	currentPackPath   string
+	currentPackCloser io.Closer
+)
+
+// assetPackFormat is the newest pack format that we understand.
+const assetPackFormat = 1
+
+// assetPack replaces some of the game's resources, e.g. for seasonal reskins.
+// A pack is a directory or a zip file with a pack.json manifest. Its other
+// files are laid out like the rsc folder, e.g. the pack's city0.png replaces
+// rsc/city0.png. Besides images, sounds and accessories.json, a pack can
+// contain:
+//
+//   - names.txt, a name list like the player's own name file.
+//   - eulogies.txt or eulogies_<language id>.txt, one eulogy per line. Each
+//     eulogy has a %s for the gopher's name followed by a %d for its number of
+//     pipes. The singular and plural forms can be given separated by " | ".
+type assetPack struct {
+	// Name identifies the pack, it must not contain spaces.
+	Name        string `json:"name"`
+	Title       string `json:"title"`
+	Author      string `json:"author"`
+	Description string `json:"description"`
+	Format      int    `json:"format"`
+
+	files fs.FS
+	names []string
+	// eulogies are keyed by language id, the empty id is for eulogies.txt.
+	eulogies map[string][]message
+}
+
+// overlayFS serves the files under rsc from the pack if it has them and from
+// the embedded resources otherwise.
+type overlayFS struct {
+	base fs.FS
+	pack fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if rest, ok := strings.CutPrefix(name, "rsc/"); ok {
+		if f, err := o.pack.Open(rest); err == nil {
+			return f, nil
+		}
+	}
+	return o.base.Open(name)
+}
+
+func (o overlayFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(o.base, name)
+	if err != nil || name != "rsc" {
+		return entries, err
+	}
+	packEntries, _ := fs.ReadDir(o.pack, ".")
+	for _, e := range packEntries {
+		i := slices.IndexFunc(entries, func(f fs.DirEntry) bool {
+			return f.Name() == e.Name()
+		})
+		if i == -1 {
+			entries = append(entries, e)
+		} else {
+			entries[i] = e
+		}
+	}
+	slices.SortFunc(entries, func(a, b fs.DirEntry) int {
+		return strings.Compare(a.Name(), b.Name())
+	})
+	return entries, nil
+}
+
+// useAssetPack loads the pack at the given path and overlays it on the
+// embedded resources. An empty path means no pack. If the pack cannot be used,
+// we keep the embedded resources and return the reason.
+func useAssetPack(path string) error {
+	if path == "" {
+		return nil
+	}
+	files, closer, err := openAssetPackFiles(path)
+	if err != nil {
+		return err
+	}
+	p, err := loadAssetPack(files)
+	if err != nil {
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}
+	if currentPackCloser != nil {
+		currentPackCloser.Close()
+	}
+	currentPack = p
+	currentPackPath = path
+	currentPackCloser = closer
+	assets = overlayFS{base: rsc, pack: p.files}
+	return nil
+}
+
+// loadAssetPack reads and validates the pack's manifest and files. Packs from
+// zip files often have all files in a single top level folder, which we allow.
+func loadAssetPack(files fs.FS) (*assetPack, error) {
+	if _, err := fs.Stat(files, "pack.json"); err != nil {
+		entries, _ := fs.ReadDir(files, ".")
+		if len(entries) != 1 || !entries[0].IsDir() {
+			return nil, errors.New("pack.json is missing")
+		}
+		files, err = fs.Sub(files, entries[0].Name())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := fs.ReadFile(files, "pack.json")
+	if err != nil {
+		return nil, errors.New("pack.json is missing")
+	}
+	var p assetPack
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("pack.json: %w", err)
+	}
+	if p.Name == "" || strings.ContainsAny(p.Name, " \t\r\n") {
+		return nil, errors.New("pack.json: the name must not be empty or contain spaces")
+	}
+	if p.Format > assetPackFormat {
+		return nil, fmt.Errorf("pack.json: format %d is newer than this game, which supports format %d", p.Format, assetPackFormat)
+	}
+	p.files = files
+
+	if err := p.validateFiles(); err != nil {
+		return nil, err
+	}
+	if err := p.validateAccessories(); err != nil {
+		return nil, err
+	}
+	if err := p.validateParallaxLayers(); err != nil {
+		return nil, err
+	}
+
+	if data, err := fs.ReadFile(files, "names.txt"); err == nil {
+		p.names = parseNameFile(data)
+		if len(p.names) == 0 {
+			return nil, errors.New("names.txt does not contain any usable names")
+		}
+	}
+
+	p.eulogies = make(map[string][]message)
+	eulogyFiles, _ := fs.Glob(files, "eulogies*.txt")
+	for _, file := range eulogyFiles {
+		lang := strings.TrimSuffix(strings.TrimPrefix(file, "eulogies"), ".txt")
+		lang = strings.TrimPrefix(lang, "_")
+		data, _ := fs.ReadFile(files, file)
+		eulogies, err := parseEulogies(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		p.eulogies[lang] = eulogies
+	}
+
+	return &p, nil
+}
+
+// validateFiles makes sure that images and sounds that replace our own can be
+// used in their place. Images must have the same size as the ones they replace
+// because the game's layout and collision depend on them.
+func (p *assetPack) validateFiles() error {
+	return fs.WalkDir(p.files, ".", func(file string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch path.Ext(file) {
+		case ".png":
+			w, h, err := imageFileSize(p.files, file)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if baseW, baseH, err := imageFileSize(rsc, "rsc/"+file); err == nil &&
+				(w != baseW || h != baseH) {
+				return fmt.Errorf("%s is %dx%d pixels but must be %dx%d", file, w, h, baseW, baseH)
+			}
+		case ".wav":
+			data, err := fs.ReadFile(p.files, file)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+				return fmt.Errorf("%s is not a WAV file", file)
+			}
+		}
+		return nil
+	})
+}
+
+// validateAccessories checks the accessory manifest that the pack uses, which
+// is either its own or ours. All accessory images must exist and have the same
+// size as the gopher.
+func (p *assetPack) validateAccessories() error {
+	files := overlayFS{base: rsc, pack: p.files}
+	data, err := fs.ReadFile(files, "rsc/accessories.json")
+	if err != nil {
+		return err
+	}
+	m, err := parseAccessoryManifest(data)
+	if err != nil {
+		return fmt.Errorf("accessories.json: %w", err)
+	}
+	return m.validateImages(files)
+}
+
+// validateParallaxLayers checks the pack's parallax.json or ours. All images of
+// all layers must exist.
+func (p *assetPack) validateParallaxLayers() error {
+	files := overlayFS{base: rsc, pack: p.files}
+	data, err := fs.ReadFile(files, "rsc/parallax.json")
+	if err != nil {
+		return err
+	}
+	layers, err := parseParallaxLayers(data)
+	if err != nil {
+		return fmt.Errorf("parallax.json: %w", err)
+	}
+	for _, l := range layers {
+		for _, image := range l.allImages() {
+			if _, _, err := imageFileSize(files, sourceImage(image)); err != nil {
+				return fmt.Errorf("image of parallax layer %q: %w", l.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+func imageFileSize(files fs.FS, file string) (w, h int, err error) {
+	data, err := fs.ReadFile(files, file)
+	if err != nil {
+		return 0, 0, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// parseEulogies reads one eulogy per line, see assetPack. Empty lines and lines
+// starting with # are ignored.
+func parseEulogies(data []byte) ([]message, error) {
+	var eulogies []message
+	for i, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		forms := strings.Split(line, " | ")
+		if len(forms) > 2 {
+			return nil, fmt.Errorf("line %d has more than two forms", i+1)
+		}
+		for _, form := range forms {
+			if !isEulogyFormat(form) {
+				return nil, fmt.Errorf("line %d needs a %%s followed by a %%d", i+1)
+			}
+			if !canDraw(form) {
+				return nil, fmt.Errorf("line %d has letters that we cannot draw", i+1)
+			}
+		}
+		if len(forms) == 2 {
+			eulogies = append(eulogies, plural(forms[0], forms[1]))
+		} else {
+			eulogies = append(eulogies, text(forms[0]))
+		}
+	}
+	return eulogies, nil
+}
+
+func isEulogyFormat(s string) bool {
+	name := strings.Index(s, "%s")
+	pipes := strings.Index(s, "%d")
+	return strings.Count(s, "%") == 2 && name != -1 && pipes != -1 && name < pipes
+}
+
+// packEulogies returns the pack's eulogies for the current language and the
+// plural rule to use for them. It returns nil if the pack has none.
+func (p *assetPack) packEulogies() ([]message, func(int) pluralCategory) {
+	if p == nil {
+		return nil, nil
+	}
+	if e := p.eulogies[currentLanguage.id]; len(e) > 0 {
+		return e, currentLanguage.plural
+	}
+	return p.eulogies[""], english.plural
+}
+
+func (p *assetPack) title() string {
+	if p.Title != "" && canDraw(p.Title) {
+		return p.Title
+	}
+	return p.Name
+}
+
+// packNames is the name source for the current pack's names.txt.
+type packNames struct {
+	pack *assetPack
+}
+
+func (packNames) id() string        { return "pack" }
+func (n packNames) title() string   { return n.pack.title() }
+func (n packNames) names() []string { return n.pack.names }
@@ -0,0 +1,47 @@
+//go:build !js
+
+package main
+
+import (
+	"archive/zip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// openAssetPackFiles opens an asset pack directory or zip file. A zip file
+// stays open until the returned closer is called, directories have no closer.
+func openAssetPackFiles(path string) (fs.FS, io.Closer, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if info.IsDir() {
+		return os.DirFS(path), nil, nil
+	}
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return z, z, nil
+}
+
+// assetPackDir is where the settings screen looks for asset packs.
+func assetPackDir() string {
+	return filepath.Join(historyDir(), "flappy_go_packs")
+}
+
+// availableAssetPacks returns the paths of all directories and zip files in the
+// assetPackDir.
+func availableAssetPacks() []string {
+	entries, _ := os.ReadDir(assetPackDir())
+	var packs []string
+	for _, e := range entries {
+		if e.IsDir() || strings.EqualFold(filepath.Ext(e.Name()), ".zip") {
+			packs = append(packs, filepath.Join(assetPackDir(), e.Name()))
+		}
+	}
+	return packs
+}
@@ -0,0 +1,19 @@
+//go:build js
+
+package main
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+)
+
+// openAssetPackFiles fails in the browser because we cannot read files from
+// the player's disk.
+func openAssetPackFiles(path string) (fs.FS, io.Closer, error) {
+	return nil, nil, errors.New("asset packs are not supported in the browser")
+}
+
+func availableAssetPacks() []string {
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"math"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// biome is a part of the world with its own look. The biomes take turns every
+// biomePipeCount pipes.
+type biome struct {
+	// name selects the images of the parallax layers, see
+	// parallaxLayer.biomeImages.
+	name              string
+	skyTop, skyBottom draw.Color
+	pipeTint          draw.Color
+	// darkness is 0 during the day and 1 in the darkest night. It darkens
+	// everything behind the pipes.
+	darkness float32
+}
+
+var biomes = []biome{
+	{
+		name:      "city",
+		skyTop:    rgb(110, 215, 255),
+		skyBottom: rgb(151, 255, 255),
+		pipeTint:  draw.White,
+	},
+	{
+		name:      "forest",
+		skyTop:    rgb(130, 205, 250),
+		skyBottom: rgb(205, 250, 225),
+		pipeTint:  draw.RGB(0.75, 0.9, 0.75),
+	},
+	{
+		name:      "desert",
+		skyTop:    rgb(100, 190, 250),
+		skyBottom: rgb(255, 235, 185),
+		pipeTint:  draw.RGB(1, 0.85, 0.55),
+	},
+	{
+		name:      "night",
+		skyTop:    rgb(10, 15, 50),
+		skyBottom: rgb(55, 65, 120),
+		pipeTint:  draw.RGB(0.5, 0.55, 0.8),
+		darkness:  1,
+	},
+}
+
+const (
+	biomePipeCount = 10
+	// biomeTransitionPipes is how long the sky takes to change from one biome
+	// to the next, measured in pipes.
+	biomeTransitionPipes = 2.0
+	// maxNightAlpha is the opacity of the nightColor in the darkest night.
+	maxNightAlpha = 0.55
+)
+
+// nightColor is laid over the background layers at night.
+var nightColor = rgb(5, 10, 40)
+
+// biomeAt returns the biome at the given position, measured in pipes. Pipe 0
+// is the first pipe of a run.
+func biomeAt(pipe float64) *biome {
+	i := int(math.Floor(pipe / biomePipeCount))
+	return &biomes[max(0, i)%len(biomes)]
+}
+
+// skyAt blends the skies of two biomes when the position is close to the
+// border between them.
+func skyAt(pipe float64) (top, bottom draw.Color, darkness float32) {
+	border := math.Round(pipe/biomePipeCount) * biomePipeCount
+	t := (pipe-border)/biomeTransitionPipes + 0.5
+	t = math.Max(0, math.Min(1, t))
+	from := biomeAt(border - 1)
+	to := biomeAt(border)
+	f := float32(t)
+	return lerpColor(from.skyTop, to.skyTop, f),
+		lerpColor(from.skyBottom, to.skyBottom, f),
+		from.darkness + (to.darkness-from.darkness)*f
+}
+
+func lerpColor(a, b draw.Color, t float32) draw.Color {
+	return draw.Color{
+		R: a.R + (b.R-a.R)*t,
+		G: a.G + (b.G-a.G)*t,
+		B: a.B + (b.B-a.B)*t,
+		A: a.A + (b.A-a.A)*t,
+	}
+}
+
+// drawSky fills the window with a vertical gradient.
+func drawSky(window renderer, top, bottom draw.Color) {
+	const stripeH = 8
+	windowW, windowH := window.Size()
+	for y := 0; y < windowH; y += stripeH {
+		t := float32(y) / float32(windowH)
+		window.FillRect(0, y, windowW, stripeH, lerpColor(top, bottom, t))
+	}
+}
+
+// drawNight darkens everything drawn so far.
+func drawNight(window renderer, darkness float32) {
+	if darkness <= 0 {
+		return
+	}
+	windowW, windowH := window.Size()
+	c := nightColor
+	c.A = darkness * maxNightAlpha
+	window.FillRect(0, 0, windowW, windowH, c)
+}
@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"io/fs"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// collisionAlpha is the smallest alpha value of a pixel that counts as solid.
+// Anti-aliased edges are mostly transparent and do not count.
+const collisionAlpha = 128
+
+// collisionMask has the solid pixels of an image. Masks that are combined
+// from several images can reach beyond the image, so the mask's rectangle is
+// relative to the image's top left corner. Masks are shared by all games, which
+// can run at the same time in env, so the caches are locked.
+type collisionMask struct {
+	x, y, w, h int
+	solid      []bool
+	// radii caches the results of radius by center, solidRect caches
+	// solidBounds and rotatedRects caches rotatedSolidBounds. cacheLock
+	// guards all three.
+	cacheLock    sync.Mutex
+	radii        map[[2]float64]float64
+	solidRect    *rectangle
+	rotatedRects map[[4]int]rectangle
+}
+
+func newCollisionMask(x, y, w, h int) *collisionMask {
+	return &collisionMask{x: x, y: y, w: w, h: h, solid: make([]bool, w*h)}
+}
+
+// at reports whether the pixel at x,y relative to the image's top left is
+// solid.
+func (m *collisionMask) at(x, y int) bool {
+	x -= m.x
+	y -= m.y
+	return 0 <= x && x < m.w && 0 <= y && y < m.h && m.solid[x+y*m.w]
+}
+
+// add makes all solid pixels of the other mask solid in this mask, with the
+// other mask moved by dx,dy. Pixels outside of this mask are lost.
+func (m *collisionMask) add(other *collisionMask, dx, dy int) {
+	for y := range other.h {
+		for x := range other.w {
+			if !other.solid[x+y*other.w] {
+				continue
+			}
+			tx, ty := other.x+x+dx-m.x, other.y+y+dy-m.y
+			if 0 <= tx && tx < m.w && 0 <= ty && ty < m.h {
+				m.solid[tx+ty*m.w] = true
+			}
+		}
+	}
+}
+
+// solidBounds is the smallest rectangle around the solid pixels, relative to
+// the image's top left corner.
+func (m *collisionMask) solidBounds() rectangle {
+	m.cacheLock.Lock()
+	defer m.cacheLock.Unlock()
+	if m.solidRect != nil {
+		return *m.solidRect
+	}
+	r := rectangle{left: m.w, top: m.h}
+	for y := range m.h {
+		for x := range m.w {
+			if m.solid[x+y*m.w] {
+				r.left, r.top = min(r.left, x), min(r.top, y)
+				r.right, r.bottom = max(r.right, x+1), max(r.bottom, y+1)
+			}
+		}
+	}
+	r.left += m.x
+	r.top += m.y
+	r.right += m.x
+	r.bottom += m.y
+	m.solidRect = &r
+	return r
+}
+
+// rotatedSolidBounds is the smallest rectangle around the screen pixels that
+// are solid in a w x h sprite of the mask, in any rotation from minRotation to
+// maxRotation degrees. It is relative to the sprite's top left corner.
+func (m *collisionMask) rotatedSolidBounds(w, h, minRotation, maxRotation int) rectangle {
+	m.cacheLock.Lock()
+	defer m.cacheLock.Unlock()
+	key := [4]int{w, h, minRotation, maxRotation}
+	if r, ok := m.rotatedRects[key]; ok {
+		return r
+	}
+
+	// The farthest corners of the rotated pixels are corners of pixels at the
+	// mask's edges, so the inner pixels can be skipped.
+	var edge [][2]float64
+	for y := range m.h {
+		for x := range m.w {
+			if m.solid[x+y*m.w] &&
+				!(m.at(m.x+x-1, m.y+y) && m.at(m.x+x+1, m.y+y) &&
+					m.at(m.x+x, m.y+y-1) && m.at(m.x+x, m.y+y+1)) {
+				edge = append(edge, [2]float64{float64(m.x + x), float64(m.y + y)})
+			}
+		}
+	}
+
+	centerX, centerY := float64(w)/2, float64(h)/2
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for rotation := minRotation; rotation <= maxRotation; rotation++ {
+		sin, cos := math.Sincos(float64(rotation) * math.Pi / 180)
+		for _, p := range edge {
+			for _, corner := range [4][2]float64{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				dx := p[0] + corner[0] - centerX
+				dy := p[1] + corner[1] - centerY
+				x := dx*cos - dy*sin + centerX
+				y := dx*sin + dy*cos + centerY
+				minX, maxX = min(minX, x), max(maxX, x)
+				minY, maxY = min(minY, y), max(maxY, y)
+			}
+		}
+	}
+	// A screen pixel is solid if its center is in a rotated solid pixel, see
+	// sprite.lookup.
+	r := rectangle{
+		left:   int(math.Ceil(minX - 0.5)),
+		top:    int(math.Ceil(minY - 0.5)),
+		right:  int(math.Floor(maxX-0.5)) + 1,
+		bottom: int(math.Floor(maxY-0.5)) + 1,
+	}
+
+	if m.rotatedRects == nil {
+		m.rotatedRects = make(map[[4]int]rectangle)
+	}
+	m.rotatedRects[key] = r
+	return r
+}
+
+// radius is the largest distance of a solid pixel's corner from the point
+// centerX,centerY. A circle with this radius covers the mask in every
+// rotation.
+func (m *collisionMask) radius(centerX, centerY float64) float64 {
+	m.cacheLock.Lock()
+	defer m.cacheLock.Unlock()
+	center := [2]float64{centerX, centerY}
+	if r, ok := m.radii[center]; ok {
+		return r
+	}
+
+	var r float64
+	for y := range m.h {
+		for x := range m.w {
+			if !m.solid[x+y*m.w] {
+				continue
+			}
+			for _, corner := range [4][2]int{{0, 0}, {1, 0}, {0, 1}, {1, 1}} {
+				dx := float64(m.x+x+corner[0]) - centerX
+				dy := float64(m.y+y+corner[1]) - centerY
+				r = max(r, math.Hypot(dx, dy))
+			}
+		}
+	}
+
+	if m.radii == nil {
+		m.radii = make(map[[2]float64]float64)
+	}
+	m.radii[center] = r
+	return r
+}
+
+// collisionMasks are loaded once per image.
+var (
+	collisionMasks     = make(map[string]*collisionMask)
+	collisionMasksLock sync.Mutex
+)
+
+// loadCollisionMask returns the mask for the image file. Tinted images have the
+// same mask as their source image.
+func loadCollisionMask(path string) (*collisionMask, error) {
+	path = sourceImage(path)
+	collisionMasksLock.Lock()
+	defer collisionMasksLock.Unlock()
+	if m, ok := collisionMasks[path]; ok {
+		return m, nil
+	}
+	data, err := fs.ReadFile(assets, path)
+	if err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	m := newCollisionMask(0, 0, b.Dx(), b.Dy())
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			_, _, _, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			m.solid[x+y*m.w] = a>>8 >= collisionAlpha
+		}
+	}
+	collisionMasks[path] = m
+	return m, nil
+}
+
+// maskLayer is an image that is drawn at an offset over the gopher.
+type maskLayer struct {
+	image            string
+	offsetX, offsetY int
+}
+
+// gopherMasks caches the combined masks of gopher frames, see gopherMask.
+var (
+	gopherMasks     = make(map[string]*collisionMask)
+	gopherMasksLock sync.Mutex
+)
+
+// gopherMask combines the masks of the gopher's images into one. The first
+// layer is the gopher itself, the others are drawn over it, e.g. its tail and
+// accessories. Layers that have no image file, like the placeholder for
+// missing accessories, are left out.
+func gopherMask(layers []maskLayer) *collisionMask {
+	var key strings.Builder
+	for _, l := range layers {
+		key.WriteString(l.image)
+		key.WriteString(" ")
+		key.WriteString(strconv.Itoa(l.offsetX))
+		key.WriteString(" ")
+		key.WriteString(strconv.Itoa(l.offsetY))
+		key.WriteString(" ")
+	}
+	gopherMasksLock.Lock()
+	defer gopherMasksLock.Unlock()
+	if m, ok := gopherMasks[key.String()]; ok {
+		return m
+	}
+
+	var masks []*collisionMask
+	var used []maskLayer
+	left, top, right, bottom := 0, 0, 0, 0
+	for i, l := range layers {
+		m, err := loadCollisionMask(l.image)
+		if err != nil {
+			continue
+		}
+		if i == 0 {
+			right, bottom = m.w, m.h
+		}
+		left = min(left, m.x+l.offsetX)
+		top = min(top, m.y+l.offsetY)
+		right = max(right, m.x+l.offsetX+m.w)
+		bottom = max(bottom, m.y+l.offsetY+m.h)
+		masks = append(masks, m)
+		used = append(used, l)
+	}
+
+	combined := newCollisionMask(left, top, right-left, bottom-top)
+	for i, m := range masks {
+		combined.add(m, used[i].offsetX, used[i].offsetY)
+	}
+	gopherMasks[key.String()] = combined
+	return combined
+}
+
+// sprite is a mask placed on the screen. The image's top left corner is at x,y
+// and it is rotated clockwise around the center of its w x h rectangle, like
+// window.DrawImageFileRotated does it.
+type sprite struct {
+	mask     *collisionMask
+	x, y     int
+	w, h     int
+	rotation int
+}
+
+func (s sprite) center() (float64, float64) {
+	return float64(s.x) + float64(s.w)/2, float64(s.y) + float64(s.h)/2
+}
+
+// upsideDown reports whether the sprite is rotated by 180 degrees. Then the
+// image's pixel x,y is at w-1-x,h-1-y, which needs no sine and cosine.
+func (s sprite) upsideDown() bool {
+	return s.rotation%360 != 0 && s.rotation%180 == 0
+}
+
+// onScreen moves a rectangle relative to the image's top left corner onto the
+// screen. It only works for sprites that are upright or upside down.
+func (s sprite) onScreen(r rectangle) rectangle {
+	if s.upsideDown() {
+		r = rectangle{
+			left:   s.w - r.right,
+			top:    s.h - r.bottom,
+			right:  s.w - r.left,
+			bottom: s.h - r.top,
+		}
+	}
+	return rectangle{
+		left:   s.x + r.left,
+		top:    s.y + r.top,
+		right:  s.x + r.right,
+		bottom: s.y + r.bottom,
+	}
+}
+
+// bounds returns a rectangle on the screen that contains all solid pixels.
+func (s sprite) bounds() rectangle {
+	if s.rotation%180 == 0 {
+		return s.onScreen(rectangle{
+			left:   s.mask.x,
+			top:    s.mask.y,
+			right:  s.mask.x + s.mask.w,
+			bottom: s.mask.y + s.mask.h,
+		})
+	}
+	centerX, centerY := s.center()
+	r := s.mask.radius(float64(s.w)/2, float64(s.h)/2)
+	return rectangle{
+		left:   int(math.Floor(centerX - r)),
+		top:    int(math.Floor(centerY - r)),
+		right:  int(math.Ceil(centerX + r)),
+		bottom: int(math.Ceil(centerY + r)),
+	}
+}
+
+// solidBounds is the smallest rectangle on the screen around the solid pixels.
+// Rotated sprites that are neither upright nor upside down get their bounds.
+func (s sprite) solidBounds() rectangle {
+	if s.rotation%180 != 0 {
+		return s.bounds()
+	}
+	return s.onScreen(s.mask.solidBounds())
+}
+
+// spritesCollide reports whether any screen pixel is solid in both sprites.
+// Every screen pixel's center is rotated back into both images to look it up
+// in their masks.
+func spritesCollide(a, b sprite) bool {
+	boundsA, boundsB := a.bounds(), b.bounds()
+	left := max(boundsA.left, boundsB.left)
+	top := max(boundsA.top, boundsB.top)
+	right := min(boundsA.right, boundsB.right)
+	bottom := min(boundsA.bottom, boundsB.bottom)
+
+	lookupA, lookupB := a.lookup(), b.lookup()
+	for y := top; y < bottom; y++ {
+		for x := left; x < right; x++ {
+			if lookupA(x, y) && lookupB(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// lookup returns a function that tells whether the screen pixel x,y is solid
+// in the sprite.
+func (s sprite) lookup() func(x, y int) bool {
+	if s.rotation%360 == 0 {
+		return func(x, y int) bool {
+			return s.mask.at(x-s.x, y-s.y)
+		}
+	}
+	if s.upsideDown() {
+		return func(x, y int) bool {
+			return s.mask.at(s.x+s.w-1-x, s.y+s.h-1-y)
+		}
+	}
+	centerX, centerY := s.center()
+	sin, cos := math.Sincos(-float64(s.rotation) * math.Pi / 180)
+	return func(x, y int) bool {
+		dx := float64(x) + 0.5 - centerX
+		dy := float64(y) + 0.5 - centerY
+		imageX := dx*cos - dy*sin + centerX - float64(s.x)
+		imageY := dx*sin + dy*cos + centerY - float64(s.y)
+		return s.mask.at(int(math.Floor(imageX)), int(math.Floor(imageY)))
+	}
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func newTestGame(t *testing.T) *game {
+	t.Helper()
+	g, err := newGame(&accessoryManifest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// solidPixels places the solid pixels of an upright or upside down sprite on
+// the screen. Unlike sprite.lookup, it goes from the image to the screen.
+func solidPixels(s sprite) map[[2]int]bool {
+	pixels := make(map[[2]int]bool)
+	m := s.mask
+	for y := range m.h {
+		for x := range m.w {
+			if !m.solid[x+y*m.w] {
+				continue
+			}
+			imageX, imageY := m.x+x, m.y+y
+			if s.rotation%360 == 0 {
+				pixels[[2]int{s.x + imageX, s.y + imageY}] = true
+			} else {
+				pixels[[2]int{s.x + s.w - 1 - imageX, s.y + s.h - 1 - imageY}] = true
+			}
+		}
+	}
+	return pixels
+}
+
+func overlap(a, b map[[2]int]bool) bool {
+	for p := range a {
+		if b[p] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupOverlap looks up every pixel around the rotated sprite a in both
+// sprites, without using their bounds.
+func lookupOverlap(a, b sprite) bool {
+	lookupA, lookupB := a.lookup(), b.lookup()
+	margin := max(a.w, a.h) / 2
+	for y := a.y - margin; y < a.y+a.h+margin; y++ {
+		for x := a.x - margin; x < a.x+a.w+margin; x++ {
+			if lookupA(x, y) && lookupB(x, y) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestGopherTouchesPipes(t *testing.T) {
+	g := newTestGame(t)
+	g.gopherXOffset = finalGopherX
+	g.x = 0
+	g.ySpeed = 0
+	g.rotation = 0
+	gap := gap{centerX: 600, centerY: windowH / 2}
+	top, bottom := g.topPipeSprite(gap), g.bottomPipeSprite(gap)
+	pipeLeft := g.bottomPipeSprite(gap).solidBounds().left
+
+	tests := []struct {
+		name string
+		pipe sprite
+		// The gopher starts at x,y and moves by dx,dy until it touches the
+		// pipe.
+		x, y   int
+		dx, dy int
+	}{
+		{"onto the bottom pipe", bottom, gap.centerX - g.gopherW/2, gap.centerY - g.gopherH, 0, 1},
+		{"onto the bottom pipe's left edge", bottom, pipeLeft - g.gopherW + 20, gap.centerY - g.gopherH, 0, 1},
+		{"into the top pipe", top, gap.centerX - g.gopherW/2, gap.centerY, 0, -1},
+		{"into the top pipe's left edge", top, pipeLeft - g.gopherW + 20, gap.centerY, 0, -1},
+		{"into the bottom pipe's front", bottom, pipeLeft - g.gopherW - 10, gap.centerY + gapHeight/2, 1, 0},
+		{"into the top pipe's front", top, pipeLeft - g.gopherW - 10, gap.centerY - gapHeight/2 - g.gopherH/2, 1, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pipePixels := solidPixels(test.pipe)
+			gopher := g.gopherSprite()
+			gopher.x, gopher.y = test.x, test.y
+			if overlap(solidPixels(gopher), pipePixels) {
+				t.Fatal("the gopher starts in the pipe")
+			}
+			for range 500 {
+				apart := gopher
+				gopher.x += test.dx
+				gopher.y += test.dy
+				if !overlap(solidPixels(gopher), pipePixels) {
+					continue
+				}
+				if !pipeCollides(gopher, test.pipe) {
+					t.Errorf("no collision at %d,%d where the pixels touch", gopher.x, gopher.y)
+				}
+				if pipeCollides(apart, test.pipe) {
+					t.Errorf("collision at %d,%d, a pixel before the pixels touch", apart.x, apart.y)
+				}
+				return
+			}
+			t.Fatal("the gopher never touched the pipe")
+		})
+	}
+}
+
+func TestGopherFitsThroughTheGap(t *testing.T) {
+	g := newTestGame(t)
+	g.gopherXOffset = finalGopherX
+	g.ySpeed = 0
+	g.rotation = 0
+	gopher := g.gopherSprite()
+	gap := gap{centerX: 0, centerY: windowH / 2}
+	// The gap is in the middle of the gopher.
+	g.x = float64(-gopher.x - g.gopherW/2)
+	top, bottom := g.topPipeSprite(gap), g.bottomPipeSprite(gap)
+
+	solid := gopher.mask.solidBounds()
+	// Between highest and lowest, the gopher's solid pixels are between the
+	// pipes' solid pixels. Beyond, it depends on the shapes.
+	highest := top.solidBounds().bottom - solid.top
+	lowest := bottom.solidBounds().top - solid.bottom
+	pipePixels := solidPixels(top)
+	for p := range solidPixels(bottom) {
+		pipePixels[p] = true
+	}
+	for y := highest - 5; y <= lowest+5; y++ {
+		gopher.y = y
+		want := overlap(solidPixels(gopher), pipePixels)
+		if highest <= y && y <= lowest && want {
+			t.Fatalf("the gopher at y %d touches the pipes inside the gap %d..%d", y, highest, lowest)
+		}
+		if have := pipeCollides(gopher, top) || pipeCollides(gopher, bottom); have != want {
+			t.Errorf("the gopher at y %d (gap %d..%d) collides: %v, want %v", y, highest, lowest, have, want)
+		}
+	}
+	gopher.y = lowest + 5
+	if !pipeCollides(gopher, bottom) {
+		t.Errorf("the gopher at y %d is 5 pixels in the bottom pipe but does not collide", gopher.y)
+	}
+}
+
+func TestGopherHitsCeilingAndFloor(t *testing.T) {
+	tests := []struct {
+		name   string
+		y      float64
+		ySpeed float64
+		cause  string
+	}{
+		{"just below the ceiling", ceilingY + 1, -0.5, ""},
+		{"at the ceiling", ceilingY + 1, -1, deathByCeiling},
+		{"above the ceiling", ceilingY - 10, 0, deathByCeiling},
+		{"just above the floor", floorY - 1, 0.5, ""},
+		{"at the floor", floorY - 1, 1, deathByFloor},
+		{"below the floor", floorY + 10, 0, deathByFloor},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			g := newTestGame(t)
+			// The first gap is still far away.
+			g.y = test.y
+			g.ySpeed = test.ySpeed
+			g.step(false)
+			if g.deathCause != test.cause {
+				t.Errorf("the gopher at %v died of %q, want %q", g.y, g.deathCause, test.cause)
+			}
+			if g.isAlive != (test.cause == "") {
+				t.Errorf("the gopher is alive: %v", g.isAlive)
+			}
+		})
+	}
+}
+
+func TestUpsideDownSpritesLookLikeRotatedOnes(t *testing.T) {
+	g := newTestGame(t)
+	pipe := g.topPipeSprite(gap{centerX: 500, centerY: 300})
+	// This is how every other rotation looks up its pixels.
+	centerX, centerY := pipe.center()
+	sin, cos := math.Sincos(-math.Pi)
+	rotated := func(x, y int) bool {
+		dx := float64(x) + 0.5 - centerX
+		dy := float64(y) + 0.5 - centerY
+		imageX := dx*cos - dy*sin + centerX - float64(pipe.x)
+		imageY := dx*sin + dy*cos + centerY - float64(pipe.y)
+		return pipe.mask.at(int(math.Floor(imageX)), int(math.Floor(imageY)))
+	}
+
+	lookup := pipe.lookup()
+	bounds := pipe.bounds()
+	solid := pipe.solidBounds()
+	for y := pipe.y - 10; y < pipe.y+pipe.h+10; y++ {
+		for x := pipe.x - 10; x < pipe.x+pipe.w+10; x++ {
+			want := rotated(x, y)
+			if lookup(x, y) != want {
+				t.Fatalf("pixel %d,%d is solid: %v, want %v", x, y, lookup(x, y), want)
+			}
+			inBounds := bounds.left <= x && x < bounds.right && bounds.top <= y && y < bounds.bottom
+			inSolid := solid.left <= x && x < solid.right && solid.top <= y && y < solid.bottom
+			if want && (!inBounds || !inSolid) {
+				t.Fatalf("solid pixel %d,%d is outside of the bounds %+v or %+v", x, y, bounds, solid)
+			}
+		}
+	}
+	if bounds.right-bounds.left != pipe.w || bounds.bottom-bounds.top != pipe.h {
+		t.Errorf("the bounds %+v are not the %dx%d image", bounds, pipe.w, pipe.h)
+	}
+}
+
+func TestRotatedGopherTouchesPipes(t *testing.T) {
+	g := newTestGame(t)
+	g.gopherXOffset = finalGopherX
+	g.x = 0
+	gap := gap{centerX: 600, centerY: windowH / 2}
+	top, bottom := g.topPipeSprite(gap), g.bottomPipeSprite(gap)
+
+	for _, rotation := range []int{-20, 12, 28, 90} {
+		g.rotation = float64(rotation)
+		gopher := g.gopherSprite()
+		gopher.x = gap.centerX - g.gopherW/2 - 30
+
+		// The collision circle contains the rotated gopher.
+		circle := gopherCollisionCircle(gopher)
+		lookup := gopher.lookup()
+		for y := gopher.y - gopher.h; y < gopher.y+2*gopher.h; y++ {
+			for x := gopher.x - gopher.w; x < gopher.x+2*gopher.w; x++ {
+				dx, dy := float64(x)+0.5-float64(circle.centerX), float64(y)+0.5-float64(circle.centerY)
+				if lookup(x, y) && math.Hypot(dx, dy) > float64(circle.radius) {
+					t.Fatalf("rotation %d: pixel %d,%d is outside of the collision circle", rotation, x, y)
+				}
+			}
+		}
+
+		for _, pipe := range []sprite{top, bottom} {
+			dy := 1
+			gopher.y = gap.centerY - g.gopherH/2
+			if pipe == top {
+				dy = -1
+			}
+			touched := false
+			for range 400 {
+				apart := gopher
+				gopher.y += dy
+				if !lookupOverlap(gopher, pipe) {
+					continue
+				}
+				if !pipeCollides(gopher, pipe) {
+					t.Errorf("rotation %d: no collision at y %d where the pixels touch", rotation, gopher.y)
+				}
+				if pipeCollides(apart, pipe) {
+					t.Errorf("rotation %d: collision at y %d, a pixel before the pixels touch", rotation, apart.y)
+				}
+				touched = true
+				break
+			}
+			if !touched {
+				t.Errorf("rotation %d: the gopher never touched the pipe", rotation)
+			}
+		}
+	}
+}
+
+func TestGopherMaskDependsOnTheOffsets(t *testing.T) {
+	still := gopherMask([]maskLayer{{image: animationFrames[0]}, {image: tailCenterImage}})
+	moved := gopherMask([]maskLayer{{image: animationFrames[0]}, {image: tailCenterImage, offsetX: -500, offsetY: 7}})
+	if still == moved {
+		t.Fatal("the layers at different offsets share their mask")
+	}
+	if moved.x != still.x-500 {
+		t.Fatalf("the tail moved by 500 pixels, the mask starts at %d instead of %d", moved.x, still.x-500)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// action is something the player can trigger with the keyboard or the mouse.
+// Every action has a list of bindings that the player can change on the
+// settings screen.
+type action int
+
+const (
+	actionFlap action = iota
+	actionSettings
+	actionName
+	actionWardrobe
+	actionFullscreen
+	actionDebug
+	actionScreenshot
+	actionPhoto
+	actionQuit
+
+	// NOTE actionCount has to come last.
+	actionCount
+)
+
+// actionIDs are used to store the bindings in the settings file. Do not change
+// them or players will lose their custom bindings.
+var actionIDs = [actionCount]string{
+	actionFlap:       "flap",
+	actionSettings:   "settings",
+	actionName:       "name",
+	actionWardrobe:   "wardrobe",
+	actionFullscreen: "fullscreen",
+	actionDebug:      "debug",
+	actionScreenshot: "screenshot",
+	actionPhoto:      "photo",
+	actionQuit:       "quit",
+}
+
+var actionNames = [actionCount]messageID{
+	actionFlap:       msgActionFlap,
+	actionSettings:   msgActionSettings,
+	actionName:       msgActionName,
+	actionWardrobe:   msgActionWardrobe,
+	actionFullscreen: msgActionFullscreen,
+	actionDebug:      msgActionDebug,
+	actionScreenshot: msgActionScreenshot,
+	actionPhoto:      msgActionPhoto,
+	actionQuit:       msgActionQuit,
+}
+
+func (a action) String() string {
+	return tr(actionNames[a])
+}
+
+func actionFromID(id string) (action, bool) {
+	for a := range actionCount {
+		if actionIDs[a] == id {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// binding is either a keyboard key or a mouse button.
+type binding struct {
+	key    draw.Key
+	button draw.MouseButton
+	mouse  bool
+}
+
+func keyBinding(key draw.Key) binding {
+	return binding{key: key}
+}
+
+func mouseBinding(button draw.MouseButton) binding {
+	return binding{button: button, mouse: true}
+}
+
+var mouseButtonNames = map[draw.MouseButton]string{
+	draw.LeftButton:   "LeftMouse",
+	draw.MiddleButton: "MiddleMouse",
+	draw.RightButton:  "RightMouse",
+}
+
+func (b binding) String() string {
+	if b.mouse {
+		return mouseButtonNames[b.button]
+	}
+	return b.key.String()
+}
+
+func parseBinding(s string) (binding, bool) {
+	for button, name := range mouseButtonNames {
+		if name == s {
+			return mouseBinding(button), true
+		}
+	}
+	for key := draw.KeyA; key <= draw.KeyPause; key++ {
+		if key.String() == s {
+			return keyBinding(key), true
+		}
+	}
+	return binding{}, false
+}
+
+// triggered reports whether the binding was pressed during the last frame.
+func (b binding) triggered(window draw.Window) bool {
+	if b.mouse {
+		for _, c := range window.Clicks() {
+			if c.Button == b.button {
+				return true
+			}
+		}
+		return false
+	}
+	return window.WasKeyPressed(b.key)
+}
+
+// controls maps every action to its bindings.
+type controls [actionCount][]binding
+
+func defaultControls() controls {
+	var c controls
+	c[actionFlap] = []binding{
+		keyBinding(draw.KeySpace),
+		keyBinding(draw.KeyUp),
+		keyBinding(draw.KeyEnter),
+		keyBinding(draw.KeyNumEnter),
+		mouseBinding(draw.LeftButton),
+	}
+	c[actionSettings] = []binding{keyBinding(draw.KeyF1)}
+	c[actionName] = []binding{keyBinding(draw.KeyF2)}
+	c[actionWardrobe] = []binding{keyBinding(draw.KeyF4)}
+	c[actionFullscreen] = []binding{keyBinding(draw.KeyF11)}
+	c[actionDebug] = []binding{keyBinding(draw.KeyF3)}
+	c[actionScreenshot] = []binding{keyBinding(draw.KeyF12)}
+	c[actionPhoto] = []binding{keyBinding(draw.KeyF6)}
+	c[actionQuit] = []binding{keyBinding(draw.KeyEscape)}
+	return c
+}
+
+// triggered reports whether any of the action's bindings was pressed during
+// the last frame.
+func (c *controls) triggered(window draw.Window, a action) bool {
+	for _, b := range c[a] {
+		if b.triggered(window) {
+			return true
+		}
+	}
+	return false
+}
+
+// required reports whether the action must always have a binding. Without
+// one, the player could not open the settings again or quit the game.
+func (a action) required() bool {
+	return a == actionSettings || a == actionQuit
+}
+
+// unbound returns the first required action that has no binding.
+func (c *controls) unbound() (action, bool) {
+	for a := range actionCount {
+		if a.required() && len(c[a]) == 0 {
+			return a, true
+		}
+	}
+	return 0, false
+}
+
+// toggle adds the binding to the action or removes it if the action already
+// has it. A binding can only ever trigger one action, so adding it removes it
+// from all other actions. If that would take the last binding from a required
+// action, nothing changes and that action is returned.
+func (c *controls) toggle(a action, b binding) (action, bool) {
+	changed := *c
+	if i := indexOfBinding(changed[a], b); i != -1 {
+		changed[a] = append(changed[a][:i:i], changed[a][i+1:]...)
+	} else {
+		for other := range changed {
+			if i := indexOfBinding(changed[other], b); i != -1 {
+				changed[other] = append(changed[other][:i:i], changed[other][i+1:]...)
+			}
+		}
+		changed[a] = append(changed[a], b)
+	}
+	if unbound, ok := changed.unbound(); ok {
+		return unbound, false
+	}
+	*c = changed
+	return 0, true
+}
+
+// clear removes all bindings of the action, unless it is required.
+func (c *controls) clear(a action) bool {
+	if a.required() {
+		return false
+	}
+	c[a] = nil
+	return true
+}
+
+func indexOfBinding(list []binding, b binding) int {
+	for i := range list {
+		if list[i] == b {
+			return i
+		}
+	}
+	return -1
+}
+
+func (c *controls) describe(a action) string {
+	if len(c[a]) == 0 {
+		return "-"
+	}
+	names := make([]string, len(c[a]))
+	for i, b := range c[a] {
+		names[i] = b.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// restartText tells the player how to start the next run, based on the
+// bindings for flapping.
+func restartText(c *controls) string {
+	if len(c[actionFlap]) == 0 {
+		return tr(msgSetUpControls, c.describe(actionSettings))
+	}
+	if slices.Contains(c[actionFlap], mouseBinding(draw.LeftButton)) {
+		return tr(msgClickToRestart)
+	}
+	return tr(msgPressToRestart, c[actionFlap][0])
+}
+
+// pressedBinding returns the first key or mouse button that was pressed during
+// the last frame.
+func pressedBinding(window draw.Window) (binding, bool) {
+	if clicks := window.Clicks(); len(clicks) > 0 {
+		return mouseBinding(clicks[0].Button), true
+	}
+	for key := draw.KeyA; key <= draw.KeyPause; key++ {
+		if window.WasKeyPressed(key) {
+			return keyBinding(key), true
+		}
+	}
+	return binding{}, false
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+func TestRequiredActionsKeepABinding(t *testing.T) {
+	c := defaultControls()
+	f1 := keyBinding(draw.KeyF1)
+	if unbound, ok := c.toggle(actionSettings, f1); ok || unbound != actionSettings {
+		t.Fatalf("removing the last binding of the settings gave %v, %v", unbound, ok)
+	}
+	if unbound, ok := c.toggle(actionFlap, f1); ok || unbound != actionSettings {
+		t.Fatalf("moving the last binding of the settings to flap gave %v, %v", unbound, ok)
+	}
+	if c.clear(actionQuit) {
+		t.Fatal("the bindings of quit were cleared")
+	}
+	if len(c[actionSettings]) != 1 || c[actionSettings][0] != f1 || len(c[actionQuit]) == 0 {
+		t.Fatalf("the controls changed to %v", c)
+	}
+
+	// With a second binding, the first one can go.
+	f3 := keyBinding(draw.KeyF3)
+	if _, ok := c.toggle(actionSettings, f3); !ok {
+		t.Fatal("F3 was not added to the settings")
+	}
+	if _, ok := c.toggle(actionFlap, f1); !ok {
+		t.Fatal("F1 was not moved from the settings to flap")
+	}
+	if len(c[actionSettings]) != 1 || c[actionSettings][0] != f3 {
+		t.Fatalf("the settings are bound to %v", c[actionSettings])
+	}
+}
+
+func TestSettingsWithoutRequiredBindingsUseTheDefaults(t *testing.T) {
+	s := bytesToSettings([]byte("bind settings\nbind quit\nbind debug\n"))
+	defaults := defaultControls()
+	for _, a := range []action{actionSettings, actionQuit} {
+		if len(s.controls[a]) == 0 || s.controls[a][0] != defaults[a][0] {
+			t.Errorf("%s is bound to %v", actionIDs[a], s.controls[a])
+		}
+	}
+	if len(s.controls[actionDebug]) != 0 {
+		t.Errorf("debug is bound to %v", s.controls[actionDebug])
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// debugOverlay shows the collision shapes and the game state on top of the
+// game, for tuning the physics. While it is open, the game can be paused with
+// P, advanced by a single step with N and slowed down with M, see update.
+type debugOverlay struct {
+	open bool
+	// paused stops the game until the next step is requested with stepOnce.
+	paused   bool
+	stepOnce bool
+	// slowMotion is an index into debugSlowdowns. The game is advanced in
+	// every slowdown'th frame.
+	slowMotion int
+	slowFrame  int
+	// frameStart is when the current frame started. frameIntervals are the
+	// times between the last frames, frameTimes are the times it took to
+	// update and draw them. Both are ring buffers.
+	frameStart     time.Time
+	frameIntervals [60]time.Duration
+	frameTimes     [60]time.Duration
+	frame          int
+}
+
+// debugSlowdowns are the speeds of the slow motion, the game runs at 1, 1/2,
+// 1/4 or 1/10 of its normal speed.
+var debugSlowdowns = []int{1, 2, 4, 10}
+
+// update handles the keys of the debug mode.
+func (d *debugOverlay) update(window draw.Window) {
+	if !d.open {
+		return
+	}
+	if window.WasKeyPressed(draw.KeyP) {
+		d.paused = !d.paused
+	}
+	if window.WasKeyPressed(draw.KeyN) {
+		d.paused = true
+		d.stepOnce = true
+	}
+	if window.WasKeyPressed(draw.KeyM) {
+		d.slowMotion = (d.slowMotion + 1) % len(debugSlowdowns)
+		d.slowFrame = 0
+	}
+}
+
+// shouldStep reports whether the game advances in this frame. Without the
+// overlay, it always does.
+func (d *debugOverlay) shouldStep() bool {
+	if !d.open {
+		return true
+	}
+	if d.paused {
+		step := d.stepOnce
+		d.stepOnce = false
+		return step
+	}
+	d.slowFrame++
+	return d.slowFrame%debugSlowdowns[d.slowMotion] == 0
+}
+
+// speed describes how fast the game runs.
+func (d *debugOverlay) speed() string {
+	if d.paused {
+		return "paused (P resume, N step, M slow motion)"
+	}
+	if n := debugSlowdowns[d.slowMotion]; n > 1 {
+		return fmt.Sprintf("speed 1/%d (P pause, N step, M slow motion)", n)
+	}
+	return "speed 1 (P pause, N step, M slow motion)"
+}
+
+// startFrame and endFrame are called around every frame to measure the frame
+// rate.
+func (d *debugOverlay) startFrame() {
+	now := time.Now()
+	if !d.frameStart.IsZero() {
+		d.frameIntervals[d.frame%len(d.frameIntervals)] = now.Sub(d.frameStart)
+	}
+	d.frameStart = now
+}
+
+func (d *debugOverlay) endFrame() {
+	d.frameTimes[d.frame%len(d.frameTimes)] = time.Since(d.frameStart)
+	d.frame++
+}
+
+// fps returns the average frames per second and the average time spent in a
+// frame over the last frames.
+func (d *debugOverlay) fps() (float64, time.Duration) {
+	var interval, frameTime time.Duration
+	n := min(d.frame, len(d.frameIntervals))
+	if n == 0 {
+		return 0, 0
+	}
+	for i := range n {
+		interval += d.frameIntervals[i]
+		frameTime += d.frameTimes[i]
+	}
+	if interval == 0 {
+		return 0, frameTime / time.Duration(n)
+	}
+	return float64(n) / interval.Seconds(), frameTime / time.Duration(n)
+}
+
+var (
+	debugCircleColor = draw.RGBA(1, 0, 1, 0.9)
+	debugRectColor   = draw.RGBA(1, 0, 0, 0.9)
+)
+
+func (d *debugOverlay) drawCircle(window renderer, c circle) {
+	window.DrawEllipse(c.centerX-c.radius, c.centerY-c.radius, 2*c.radius, 2*c.radius, debugCircleColor)
+	window.FillRect(c.centerX-1, c.centerY-1, 3, 3, debugCircleColor)
+}
+
+func (d *debugOverlay) drawRect(window renderer, r rectangle) {
+	window.DrawRect(r.left, r.top, r.right-r.left, r.bottom-r.top, debugRectColor)
+}
+
+// drawState lists the state, one line per entry, in the top left corner.
+func (d *debugOverlay) drawState(window renderer, lines []string) {
+	fps, frameTime := d.fps()
+	lines = append([]string{
+		fmt.Sprintf("FPS %.1f, frame %.2f ms", fps, float64(frameTime.Microseconds())/1000),
+		d.speed(),
+	}, lines...)
+
+	const (
+		textScale = 1.4
+		margin    = 8
+	)
+	w, h := 0, 0
+	for _, line := range lines {
+		lineW, lineH := window.GetScaledTextSize(line, textScale)
+		w = max(w, lineW)
+		h += lineH
+	}
+	window.FillRect(0, 0, w+2*margin, h+2*margin, draw.RGBA(0, 0, 0, 0.6))
+	y := margin
+	for _, line := range lines {
+		window.DrawScaledText(line, margin, y, textScale, draw.White)
+		_, lineH := window.GetScaledTextSize(line, textScale)
+		y += lineH
+	}
+}
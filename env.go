@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"math/rand"
+	"slices"
+)
+
+// envGapCount is how many of the next gaps an observation has.
+const envGapCount = 2
+
+// observation is what an agent sees of the game, see env.
+type observation struct {
+	// Y is the gopher's center, YSpeed is positive when it falls.
+	Y      float64 `json:"y"`
+	YSpeed float64 `json:"ySpeed"`
+	// Gaps are the next gaps that the gopher has not passed yet, nearest
+	// first.
+	Gaps []observedGap `json:"gaps"`
+}
+
+type observedGap struct {
+	// DX is the horizontal distance from the gopher's center to the gap's
+	// center, it gets negative while the gopher is in the gap.
+	DX      float64 `json:"dx"`
+	CenterY int     `json:"centerY"`
+}
+
+// observe returns what the gopher sees. All values are in screen pixels.
+func (g *game) observe() observation {
+	gopherX := g.gopherWorldX()
+	gopherLeft := gopherX - float64(g.gopherW/2)
+	var ahead []gap
+	for _, gap := range g.gaps {
+		if float64(gap.centerX+g.pipeW/2) >= gopherLeft {
+			ahead = append(ahead, gap)
+		}
+	}
+	slices.SortFunc(ahead, func(a, b gap) int { return a.centerX - b.centerX })
+	obs := observation{Y: g.gopherCenterY(), YSpeed: g.ySpeed}
+	for _, gap := range ahead[:min(envGapCount, len(ahead))] {
+		obs.Gaps = append(obs.Gaps, observedGap{
+			DX:      float64(gap.centerX) - gopherX,
+			CenterY: gap.centerY,
+		})
+	}
+	return obs
+}
+
+// env is the game as a reinforcement learning environment, in the style of
+// OpenAI Gym. An agent resets it with a seed and then steps it, flapping or
+// not, until it is done. The reward is 1 for every pipe that the gopher
+// passes.
+type env struct {
+	g       *game
+	started bool
+}
+
+// envRequest is a line that the agent sends, it is answered with an
+// envResponse. Cmd is "reset" or "step". Reset uses Seed if it is given and a
+// random seed otherwise, step uses Flap.
+type envRequest struct {
+	Cmd  string `json:"cmd"`
+	Seed *int64 `json:"seed"`
+	Flap bool   `json:"flap"`
+}
+
+type envResponse struct {
+	Obs    *observation `json:"obs,omitempty"`
+	Reward int          `json:"reward"`
+	Done   bool         `json:"done"`
+	Score  int          `json:"score"`
+	Seed   int64        `json:"seed"`
+	Error  string       `json:"error,omitempty"`
+}
+
+func newEnv(m *accessoryManifest) (*env, error) {
+	g, err := newGame(m)
+	if err != nil {
+		return nil, err
+	}
+	return &env{g: g}, nil
+}
+
+func (e *env) reset(seed int64) observation {
+	e.g.reset(seed, nil)
+	e.started = true
+	return e.g.observe()
+}
+
+// step advances the game by a frame. Once the gopher is dead, the episode is
+// done and the game does not move anymore until the next reset.
+func (e *env) step(flap bool) (obs observation, reward int, done bool, err error) {
+	if !e.started {
+		return observation{}, 0, false, errors.New("reset the environment before stepping it")
+	}
+	if e.g.isAlive {
+		score := e.g.score
+		e.g.step(flap)
+		reward = e.g.score - score
+	}
+	return e.g.observe(), reward, !e.g.isAlive, nil
+}
+
+// handle answers a request.
+func (e *env) handle(req envRequest) envResponse {
+	switch req.Cmd {
+	case "reset":
+		seed := rand.Int63()
+		if req.Seed != nil {
+			seed = *req.Seed
+		}
+		obs := e.reset(seed)
+		return envResponse{Obs: &obs, Seed: seed}
+	case "step":
+		obs, reward, done, err := e.step(req.Flap)
+		if err != nil {
+			return envResponse{Error: err.Error()}
+		}
+		return envResponse{Obs: &obs, Reward: reward, Done: done, Score: e.g.score, Seed: e.g.seed}
+	}
+	return envResponse{Error: "unknown command " + req.Cmd + ", use reset or step"}
+}
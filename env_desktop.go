@@ -0,0 +1,90 @@
+//go:build !js
+
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+)
+
+// runEnv is the env command, see the readme. It serves the game as a
+// reinforcement learning environment on stdin and stdout or, with -listen, on
+// a TCP port where every connection gets its own game.
+func runEnv(args []string, m *accessoryManifest) error {
+	flags := flag.NewFlagSet("env", flag.ContinueOnError)
+	listen := flags.String("listen", "", "TCP `address` to serve on, e.g. :4000, instead of stdin and stdout")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *listen == "" {
+		e, err := newEnv(m)
+		if err != nil {
+			return err
+		}
+		return e.serve(os.Stdin, os.Stdout)
+	}
+
+	// Without a host, only serve this computer.
+	addr := *listen
+	if strings.HasPrefix(addr, ":") {
+		addr = "localhost" + addr
+	}
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	fmt.Fprintln(os.Stderr, "listening on", l.Addr())
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			e, err := newEnv(m)
+			if err != nil {
+				fmt.Fprintln(conn, err)
+				return
+			}
+			if err := e.serve(conn, conn); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}()
+	}
+}
+
+// serve reads one JSON request per line and writes one JSON response per line
+// until the input ends.
+func (e *env) serve(r io.Reader, w io.Writer) error {
+	lines := bufio.NewScanner(r)
+	out := bufio.NewWriter(w)
+	encoder := json.NewEncoder(out)
+	for lines.Scan() {
+		line := strings.TrimSpace(lines.Text())
+		if line == "" {
+			continue
+		}
+		var req envRequest
+		resp := envResponse{}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp.Error = "invalid request: " + err.Error()
+		} else {
+			resp = e.handle(req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+	}
+	return lines.Err()
+}
@@ -0,0 +1,9 @@
+//go:build js
+
+package main
+
+import "errors"
+
+func runEnv(args []string, m *accessoryManifest) error {
+	return errors.New("the environment is not available in the browser")
+}
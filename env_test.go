@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"testing"
+)
+
+// playEnv plays a run with a simple policy and returns the score after every
+// step.
+func playEnv(e *env, seed int64) ([]int, error) {
+	obs := e.reset(seed)
+	var scores []int
+	for range 3000 {
+		flap := obs.YSpeed > 0 && len(obs.Gaps) > 0 && obs.Y > float64(obs.Gaps[0].CenterY)
+		var done bool
+		var err error
+		obs, _, done, err = e.step(flap)
+		if err != nil {
+			return nil, err
+		}
+		scores = append(scores, e.g.score)
+		if done {
+			break
+		}
+	}
+	return scores, nil
+}
+
+func TestEnvsRunSideBySide(t *testing.T) {
+	seeds := []int64{1, 2, 3, 4}
+	// Like the connections of env -listen, every env has its own goroutine.
+	have := make([][]int, len(seeds))
+	errs := make([]error, len(seeds))
+	var wg sync.WaitGroup
+	for i, seed := range seeds {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			e, err := newEnv(&accessoryManifest{})
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			have[i], errs[i] = playEnv(e, seed)
+		}()
+	}
+	wg.Wait()
+
+	// Alone, the runs must be the same.
+	want := make([][]int, len(seeds))
+	for i, seed := range seeds {
+		e, err := newEnv(&accessoryManifest{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want[i], err = playEnv(e, seed); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, seed := range seeds {
+		if errs[i] != nil {
+			t.Fatalf("seed %d: %v", seed, errs[i])
+		}
+		if len(have[i]) != len(want[i]) {
+			t.Fatalf("seed %d: the run took %d steps side by side and %d alone", seed, len(have[i]), len(want[i]))
+		}
+		for step := range have[i] {
+			if have[i][step] != want[i][step] {
+				t.Fatalf("seed %d: the score after step %d is %d side by side and %d alone", seed, step, have[i][step], want[i][step])
+			}
+		}
+	}
+}
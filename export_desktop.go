@@ -0,0 +1,298 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/gif"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// stepsPerSecond is how many steps the game makes per second, the window runs
+// at 60 frames per second.
+const stepsPerSecond = 60
+
+// runExport is the export command, see the readme. It replays a run from the
+// kill history without a window and writes it as an animated GIF or as a PNG
+// per frame.
+func runExport(args []string, m *accessoryManifest) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	list := flags.Bool("list", false, "list the kills that can be exported")
+	killNumber := flags.Int("kill", -1, "`number` of the kill to export as shown by -list, negative numbers count from the last kill")
+	format := flags.String("format", "gif", "gif or png, png writes a numbered image per frame into a folder")
+	width := flags.Int("width", windowW/2, "image width in pixels")
+	height := flags.Int("height", windowH/2, "image height in pixels")
+	fps := flags.Int("fps", 30, "frames per second, one of "+strings.Join(exportFPS(), ", "))
+	before := flags.Float64("before", 0, "`seconds` before the death to start at, 0 starts at the beginning of the run")
+	after := flags.Float64("after", 2, "`seconds` after the death to end at")
+	out := flags.String("out", "", "output file for gif, folder for png, the default is named after the gopher")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	kills := loadKillHistory()
+	if *list {
+		for i, k := range kills {
+			fmt.Printf("%d: %s, %d pipes", i+1, k.Name, k.Score)
+			if k.Replay == nil {
+				fmt.Print(" (no replay)")
+			}
+			fmt.Println()
+		}
+		return nil
+	}
+
+	if len(kills) == 0 {
+		return errors.New("no gopher has died yet")
+	}
+	i := *killNumber - 1
+	if *killNumber < 0 {
+		i = len(kills) + *killNumber
+	}
+	if i < 0 || i >= len(kills) {
+		return fmt.Errorf("there is no kill number %d, there are %d kills", *killNumber, len(kills))
+	}
+	k := kills[i]
+	if k.Replay == nil {
+		return fmt.Errorf("%s died in an older version of the game which did not record replays", k.Name)
+	}
+	if *format != "gif" && *format != "png" {
+		return fmt.Errorf("unknown format %q, use gif or png", *format)
+	}
+	if *width <= 0 || *height <= 0 {
+		return errors.New("width and height must be positive")
+	}
+	if *fps <= 0 || stepsPerSecond%*fps != 0 {
+		return fmt.Errorf("fps must be one of %s", strings.Join(exportFPS(), ", "))
+	}
+	if *out == "" {
+		*out = fileName(k.Name, k.Score)
+		if *format == "gif" {
+			*out += ".gif"
+		}
+	}
+
+	g, err := newGame(m)
+	if err != nil {
+		return err
+	}
+
+	// Replay the run once to find out when the gopher dies.
+	k.Replay.start(g, k.Accessories)
+	lastFlap := 0
+	if len(k.Replay.Flaps) > 0 {
+		lastFlap = k.Replay.Flaps[len(k.Replay.Flaps)-1]
+	}
+	for !g.died && g.steps <= lastFlap+60*stepsPerSecond {
+		g.step(k.Replay.flapsIn(g.steps))
+	}
+	if !g.died {
+		return fmt.Errorf("%s does not die in the replay", k.Name)
+	}
+	if g.score != k.Score {
+		fmt.Fprintf(os.Stderr, "the replay differs from the run, %s cleared %d pipes in the replay and %d in the run\n",
+			k.Name, g.score, k.Score)
+	}
+	death := g.steps
+	first := 0
+	if *before > 0 {
+		first = max(0, death-round(*before*stepsPerSecond))
+	}
+	last := death + round(*after*stepsPerSecond)
+
+	// Replay it again, drawing the frames.
+	highscore := 0
+	for _, k := range kills[:i] {
+		highscore = max(highscore, k.Score)
+	}
+	k.Replay.start(g, k.Accessories)
+	g.highscore = highscore
+	killCount := i
+	window := newScaledSoftwareRenderer(windowW, windowH, *width, *height)
+	stepsPerFrame := stepsPerSecond / *fps
+	var frames frameWriter
+	if *format == "png" {
+		if err := os.MkdirAll(*out, 0777); err != nil {
+			return err
+		}
+		frames = &pngFrames{folder: *out}
+	} else {
+		frames = &gifFrames{
+			path:      *out,
+			frameTime: float64(stepsPerFrame) / stepsPerSecond,
+			lookup:    newPaletteLookup(palette.Plan9),
+		}
+	}
+	for g.steps < last {
+		g.step(k.Replay.flapsIn(g.steps))
+		if g.steps < first || (g.steps-first)%stepsPerFrame != 0 {
+			continue
+		}
+		if g.restartable() {
+			killCount = i + 1
+		}
+		g.draw(window)
+		g.drawName(window, k.Name)
+		drawHUD(window, hud{
+			score:              g.score,
+			scoreAnimationTime: g.scoreAnimationTime,
+			highscore:          g.highscore,
+			killCount:          killCount,
+			name:               k.Name,
+			nameAlpha:          g.nameAlpha,
+			playingAlpha:       float32(g.deceasedTextTime) / deceasedTextFadeFrameCount,
+			backgroundColor:    backgroundColor,
+		})
+		if err := frames.add(window.image); err != nil {
+			return err
+		}
+	}
+	return frames.close()
+}
+
+// exportFPS lists the frame rates that the export supports. Every frame shows
+// the same number of steps, so the frame rate must divide the steps per second.
+func exportFPS() []string {
+	var list []string
+	for fps := 1; fps <= stepsPerSecond; fps++ {
+		if stepsPerSecond%fps == 0 {
+			list = append(list, strconv.Itoa(fps))
+		}
+	}
+	return list
+}
+
+// frameWriter gets the exported frames one after the other, so they do not all
+// have to be kept in memory.
+type frameWriter interface {
+	add(frame *image.RGBA) error
+	close() error
+}
+
+// pngFrames writes the frames as frame_0001.png, frame_0002.png and so on into
+// the folder.
+type pngFrames struct {
+	folder string
+	count  int
+}
+
+func (p *pngFrames) add(frame *image.RGBA) error {
+	p.count++
+	return writePNG(filepath.Join(p.folder, fmt.Sprintf("frame_%04d.png", p.count)), frame)
+}
+
+func (p *pngFrames) close() error {
+	fmt.Printf("wrote %d frames to %s\n", p.count, p.folder)
+	return nil
+}
+
+func writePNG(path string, img image.Image) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(f, img); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// gifFrames collects the frames for an endless animation, each frame shown for
+// frameTime seconds, and writes it on close.
+type gifFrames struct {
+	path      string
+	frameTime float64
+	lookup    *paletteLookup
+	anim      gif.GIF
+}
+
+func (a *gifFrames) add(frame *image.RGBA) error {
+	// GIF delays are in 100ths of a second. We round the time since the start
+	// instead of every delay so the errors do not add up.
+	n := float64(len(a.anim.Image))
+	delay := round((n+1)*a.frameTime*100) - round(n*a.frameTime*100)
+	a.anim.Image = append(a.anim.Image, a.lookup.dither(frame))
+	a.anim.Delay = append(a.anim.Delay, delay)
+	return nil
+}
+
+func (a *gifFrames) close() error {
+	f, err := os.Create(a.path)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(f, &a.anim); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("wrote %d frames to %s\n", len(a.anim.Image), a.path)
+	return nil
+}
+
+// paletteLookup has the closest palette color for all colors with 5 bits per
+// channel. Searching the palette for every pixel is too slow for long runs.
+type paletteLookup struct {
+	palette color.Palette
+	index   [32 * 32 * 32]uint8
+}
+
+func newPaletteLookup(p color.Palette) *paletteLookup {
+	l := &paletteLookup{palette: p}
+	for i := range l.index {
+		r, g, b := uint8(i>>10), uint8(i>>5&31), uint8(i&31)
+		c := color.RGBA{r<<3 | r>>2, g<<3 | g>>2, b<<3 | b>>2, 255}
+		l.index[i] = uint8(p.Index(c))
+	}
+	return l
+}
+
+// dither converts the image to the palette with Floyd-Steinberg dithering, so
+// the sky's gradient does not turn into stripes.
+func (l *paletteLookup) dither(img *image.RGBA) *image.Paletted {
+	bounds := img.Bounds()
+	out := image.NewPaletted(bounds, l.palette)
+	// carry has the color errors that are carried to the pixels of the
+	// current and the next row, with a column of padding on either side.
+	w := bounds.Dx()
+	carry := [2][][3]int{make([][3]int, w+2), make([][3]int, w+2)}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		cur, next := carry[0], carry[1]
+		clear(next)
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			e := &cur[x-bounds.Min.X+1]
+			want := [3]int{
+				min(255, max(0, int(c.R)+e[0]/16)),
+				min(255, max(0, int(c.G)+e[1]/16)),
+				min(255, max(0, int(c.B)+e[2]/16)),
+			}
+			i := l.index[want[0]>>3<<10|want[1]>>3<<5|want[2]>>3]
+			out.SetColorIndex(x, y, i)
+			r, g, b, _ := l.palette[i].RGBA()
+			have := [3]int{int(r >> 8), int(g >> 8), int(b >> 8)}
+			for ch := range 3 {
+				diff := want[ch] - have[ch]
+				col := x - bounds.Min.X + 1
+				cur[col+1][ch] += 7 * diff
+				next[col-1][ch] += 3 * diff
+				next[col][ch] += 5 * diff
+				next[col+1][ch] += 1 * diff
+			}
+		}
+		carry[0], carry[1] = next, cur
+	}
+	return out
+}
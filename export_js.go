@@ -0,0 +1,9 @@
+//go:build js
+
+package main
+
+import "errors"
+
+func runExport(args []string, m *accessoryManifest) error {
+	return errors.New("exporting runs is not available in the browser")
+}
@@ -0,0 +1,631 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+const (
+	windowW, windowH     = 1500, 800
+	gopherSpeed          = 5
+	gravity              = 0.5
+	gapHeight            = 300
+	firstGapX            = 1300
+	gapDistX             = 600
+	finalGopherX         = 100
+	clickYSpeed          = -14.0
+	minVisiblePipeHeight = 80
+	pipeShakeFrameCount  = 40
+	// The gopher dies when its y reaches the ceiling or the floor.
+	ceilingY = -30
+	floorY   = windowH - 145
+	// The gopher turns towards rotationPerYSpeed times its speed, in degrees.
+	rotationPerYSpeed = 1.5
+)
+
+var animationFrames = []string{
+	"rsc/arms_center.png",
+	"rsc/arms_up.png",
+	"rsc/arms_center.png",
+	"rsc/arms_down.png",
+}
+
+// game is the simulation of a gopher's run. It does not draw, play sounds or
+// read input itself, so the same game runs in the window, in a terminal and
+// without any output at all. All coordinates are in the windowW x windowH
+// screen, except for the gaps which are in world coordinates, x is how far the
+// screen has scrolled into the world.
+type game struct {
+	// accessoryCollision makes the gopher's accessories count for collisions
+	// with the pipes, see settings.
+	accessoryCollision bool
+	accessories        []string
+	manifest           *accessoryManifest
+
+	animationIndex      int
+	nextFlapIn          int
+	gopherXOffset       int
+	x                   float64
+	y                   float64
+	xSpeed              float64
+	ySpeed              float64
+	rotation            float64
+	targetRotation      float64
+	isAlive             bool
+	gaps                [10]gap
+	nextGapX            int
+	score               int
+	scoreAnimationTime  float64
+	highscore           int
+	needToPlayFlapSound bool
+	flapSoundCoolDown   int
+	playDeathSoundIn    int
+	bumpOnHead          bool
+	weather             *weatherState
+	particles           particleSystem
+	parallax            []*parallaxLayer
+	nameAlpha           float32
+	nameAnimationTime   int
+	deceasedTextTime    int
+	killScrollY         int
+
+	// rand makes the gaps, it is seeded in reset.
+	rand *rand.Rand
+	// gapPattern is the name of the gapPatterns entry that places the gaps, it
+	// is used from the next reset on. gapGenerator places them in the current
+	// run, lastGapY is the center of the last gap that it placed.
+	gapPattern   string
+	gapGenerator gapGenerator
+	lastGapY     int
+	// flight checks that the gaps can be flown through, see solvableGaps.
+	flight *flightModel
+	// seed is the seed of the current run. steps counts the steps since the
+	// start of the run and flaps are the steps in which the gopher flapped.
+	// Together they replay the run, see replay.
+	seed  int64
+	steps int
+	flaps []int
+
+	// sounds are the sound files that the last step wants to be played.
+	sounds []string
+	// died is true if the gopher died in the last step.
+	died bool
+	// deathCause is what killed the gopher, one of the deathBy constants, or
+	// "" while it is alive.
+	deathCause string
+
+	gopherW, gopherH int
+	pipeW, pipeH     int
+	pipeMask         *collisionMask
+	// imageSizes caches the sizes of the parallax images, see ImageSize.
+	imageSizes map[string][2]int
+}
+
+// The causes of death, see game.deathCause.
+const (
+	deathByCeiling    = "ceiling"
+	deathByFloor      = "floor"
+	deathByTopPipe    = "top pipe"
+	deathByBottomPipe = "bottom pipe"
+)
+
+// deceasedTextFadeFrameCount is how long "now playing" takes to turn into
+// "recently deceased".
+const deceasedTextFadeFrameCount = 60
+
+// newGame loads the image sizes and collision masks that the game needs.
+func newGame(m *accessoryManifest) (*game, error) {
+	g := &game{manifest: m, parallax: loadParallaxLayers(), gapPattern: defaultGapPattern}
+	var err error
+	g.gopherW, g.gopherH, err = imageFileSize(assets, animationFrames[0])
+	if err != nil {
+		return nil, err
+	}
+	g.pipeW, g.pipeH, err = imageFileSize(assets, pipeImage)
+	if err != nil {
+		return nil, err
+	}
+	g.pipeMask, err = loadCollisionMask(pipeImage)
+	if err != nil {
+		return nil, err
+	}
+	// The flight model's gopher covers all of its frames in all rotations.
+	minRotation, maxRotation := flightRotations()
+	gopherBounds := rectangle{left: g.gopherW, top: g.gopherH}
+	for _, frame := range animationFrames {
+		for _, tail := range []string{tailDownImage, tailCenterImage, tailUpImage} {
+			mask := gopherMask([]maskLayer{{image: frame}, {image: tail}})
+			b := mask.rotatedSolidBounds(g.gopherW, g.gopherH, minRotation, maxRotation)
+			gopherBounds.left = min(gopherBounds.left, b.left)
+			gopherBounds.top = min(gopherBounds.top, b.top)
+			gopherBounds.right = max(gopherBounds.right, b.right)
+			gopherBounds.bottom = max(gopherBounds.bottom, b.bottom)
+		}
+	}
+	g.flight = newFlightModel(gopherBounds, g.pipeMask.solidBounds(), g.pipeW)
+	g.reset(0, nil)
+	return g, nil
+}
+
+// reset starts a new run. Everything random during the run comes from the
+// seed, so the same seed gives the same gaps, weather and particles.
+func (g *game) reset(seed int64, accessories []string) {
+	runRand := rand.New(rand.NewSource(seed))
+	g.seed = seed
+	g.steps = 0
+	g.flaps = g.flaps[:0]
+	g.accessories = accessories
+	g.animationIndex = 0
+	g.nextFlapIn = 0
+	g.gopherXOffset = -finalGopherX - 150
+	g.x = 0.0
+	g.y = 400.0
+	g.xSpeed = 0.0
+	g.ySpeed = clickYSpeed
+	g.rotation = 0.0
+	g.targetRotation = 0.0
+	g.isAlive = true
+	g.rand = rand.New(rand.NewSource(runRand.Int63()))
+	// The gaps are placed for the weather's wind, so it comes first. The gaps
+	// only use g.rand, so runRand's numbers go to the same places as in older
+	// runs.
+	g.weather = newWeatherState(runRand.Int63())
+	g.gapGenerator = g.newGapGenerator()
+	g.lastGapY = firstGapY
+	g.nextGapX = firstGapX
+	for i := range g.gaps {
+		g.gaps[i] = gap{}
+		g.gaps[i].centerX = g.nextGapX
+		g.gaps[i].centerY = g.nextGapY()
+		g.nextGapX += gapDistX
+	}
+	g.score = 0
+	g.scoreAnimationTime = 0.0
+	g.needToPlayFlapSound = true
+	g.flapSoundCoolDown = 0
+	g.playDeathSoundIn = 0
+	g.bumpOnHead = false
+	g.particles.reset(runRand.Int63())
+	for _, layer := range g.parallax {
+		layer.reset(runRand.Int63())
+	}
+	g.nameAlpha = 1.0
+	g.nameAnimationTime = 0
+	g.deceasedTextTime = deceasedTextFadeFrameCount
+	g.killScrollY = 0
+	g.sounds = nil
+	g.died = false
+	g.deathCause = ""
+}
+
+// newGapGenerator makes the generator for the gapPattern. Unknown patterns use
+// the default one. It is called in reset, when the gopher is at its start.
+func (g *game) newGapGenerator() gapGenerator {
+	newPattern, ok := gapPatterns[g.gapPattern]
+	if !ok {
+		newPattern = gapPatterns[defaultGapPattern]
+	}
+	if g.gapPattern == uncheckedGapPattern || g.flight == nil {
+		return newPattern()
+	}
+	x := round(g.x) + g.gopherXOffset + finalGopherX
+	return newSolvableGaps(newPattern(), g.flight, g.weather.gusts, x, g.steps, g.y, g.ySpeed)
+}
+
+func (g *game) nextGapY() int {
+	g.lastGapY = g.gapGenerator.nextGapY(g.rand, g.lastGapY)
+	return g.lastGapY
+}
+
+// restartable is true once the gopher has fallen far below the screen.
+func (g *game) restartable() bool {
+	return g.y > 3*windowH
+}
+
+func (g *game) tailImage() string {
+	if !g.isAlive || g.ySpeed < -7 {
+		return tailDownImage
+	}
+	if g.ySpeed > 7 {
+		return tailUpImage
+	}
+	return tailCenterImage
+}
+
+func (g *game) gopherImage() string {
+	if g.isAlive {
+		return animationFrames[g.animationIndex]
+	}
+	if g.bumpOnHead {
+		return bumpFrame
+	}
+	return deadFrame
+}
+
+// gopherWorldX and gopherCenterY are where the gopher's center is, for
+// particles.
+func (g *game) gopherWorldX() float64 {
+	return g.x + float64(g.gopherXOffset+finalGopherX+g.gopherW/2)
+}
+
+func (g *game) gopherCenterY() float64 {
+	return g.y + float64(g.gopherH/2)
+}
+
+// The gopher's collision circle contains all of its solid pixels in every
+// rotation, so only the pipes that touch the circle have to be checked pixel by
+// pixel.
+func (g *game) gopherSprite() sprite {
+	image := animationFrames[g.animationIndex]
+	layers := []maskLayer{{image: image}, {image: g.tailImage()}}
+	if g.accessoryCollision {
+		for _, a := range g.manifest.layers(g.accessories) {
+			layers = append(layers, maskLayer{a.Image, a.OffsetX, a.OffsetY})
+		}
+	}
+	return sprite{
+		mask:     gopherMask(layers),
+		x:        g.gopherXOffset + finalGopherX,
+		y:        round(g.y),
+		w:        g.gopherW,
+		h:        g.gopherH,
+		rotation: round(g.rotation),
+	}
+}
+
+func gopherCollisionCircle(gopher sprite) circle {
+	centerX, centerY := gopher.center()
+	return circle{
+		centerX: round(centerX),
+		centerY: round(centerY),
+		radius:  int(math.Ceil(gopher.mask.radius(float64(gopher.w)/2, float64(gopher.h)/2))) + 1,
+	}
+}
+
+func (g *game) topPipeSprite(gap gap) sprite {
+	return sprite{
+		mask:     g.pipeMask,
+		x:        gap.centerX - g.pipeW/2 - round(g.x),
+		y:        gap.centerY - gapHeight/2 - g.pipeH,
+		w:        g.pipeW,
+		h:        g.pipeH,
+		rotation: 180,
+	}
+}
+
+func (g *game) bottomPipeSprite(gap gap) sprite {
+	return sprite{
+		mask: g.pipeMask,
+		x:    gap.centerX - g.pipeW/2 - round(g.x),
+		y:    gap.centerY + gapHeight/2,
+		w:    g.pipeW,
+		h:    g.pipeH,
+	}
+}
+
+func pipeCollides(gopher sprite, pipe sprite) bool {
+	return collides(gopherCollisionCircle(gopher), pipe.solidBounds()) &&
+		spritesCollide(gopher, pipe)
+}
+
+// step advances the game by one frame. If clicked is true, the gopher flaps.
+func (g *game) step(clicked bool) {
+	g.sounds = g.sounds[:0]
+	g.died = false
+	restartable := g.restartable()
+
+	if g.isAlive && clicked {
+		g.flaps = append(g.flaps, g.steps)
+		g.ySpeed = clickYSpeed
+		g.nextFlapIn = 0
+		g.needToPlayFlapSound = true
+		g.particles.emit(flapEmitter, g.gopherWorldX()-40, g.gopherCenterY()+30)
+		g.particles.emit(featherEmitter, g.gopherWorldX()-30, g.gopherCenterY())
+	}
+
+	g.flapSoundCoolDown--
+
+	if g.needToPlayFlapSound && g.flapSoundCoolDown <= 0 {
+		g.sounds = append(g.sounds, "rsc/flap.wav")
+		g.flapSoundCoolDown = 30
+	}
+
+	g.needToPlayFlapSound = false
+
+	g.nextFlapIn--
+	if g.nextFlapIn <= 0 {
+		const (
+			slowestFlapYSpeed = 10.0
+			minFlapIn         = 1
+			maxFlapIn         = 10
+		)
+		relative := (g.ySpeed - clickYSpeed) / (slowestFlapYSpeed - clickYSpeed)
+		g.nextFlapIn = round(minFlapIn + relative*(maxFlapIn-minFlapIn))
+		g.animationIndex = (g.animationIndex + 1) % len(animationFrames)
+	}
+
+	if g.gopherXOffset < finalGopherX {
+		// Slide in the gopher into the screen.
+		g.gopherXOffset = min(g.gopherXOffset+gopherSpeed, finalGopherX)
+
+		if g.gopherXOffset == finalGopherX {
+			g.xSpeed = gopherSpeed
+		}
+	}
+
+	if g.gopherXOffset == finalGopherX {
+		g.nameAlpha = max(g.nameAlpha-0.33/60.0, 0)
+	}
+
+	if !g.isAlive && g.xSpeed > 0 {
+		g.xSpeed = max(0, g.xSpeed-0.15)
+	}
+
+	g.x += g.xSpeed
+	for i := range g.gaps {
+		if g.gaps[i].centerX-round(g.x) < -g.pipeW/2 {
+			g.gaps[i] = gap{}
+			g.gaps[i].centerX = g.nextGapX
+			g.gaps[i].centerY = g.nextGapY()
+			g.nextGapX += gapDistX
+
+			g.score++
+			g.sounds = append(g.sounds, "rsc/score.wav")
+			g.particles.emit(scoreEmitter, g.gopherWorldX(), g.gopherCenterY())
+
+			if g.score > g.highscore {
+				g.highscore = g.score
+			}
+
+			g.scoreAnimationTime = 1.0
+		}
+	}
+	g.y += g.ySpeed
+	g.ySpeed += gravity
+	if g.isAlive {
+		g.ySpeed += g.weather.force()
+	}
+
+	// The gopher is rotated before the collision checks, so it collides in the
+	// pose that it is drawn in.
+	g.targetRotation = g.ySpeed * rotationPerYSpeed
+	g.rotation = 0.5*g.targetRotation + 0.5*g.rotation
+
+	wasAlive := g.isAlive
+
+	if g.isAlive && g.y <= ceilingY {
+		// Drop dead on hitting the ceiling.
+		g.isAlive = false
+		g.deathCause = deathByCeiling
+		g.ySpeed = 0
+		g.bumpOnHead = true
+		g.sounds = append(g.sounds, "rsc/hit_ceiling.wav")
+		g.particles.emit(ceilingEmitter, g.gopherWorldX(), g.gopherCenterY()-40)
+		g.playDeathSoundIn = 30
+	}
+	if g.isAlive && g.y >= floorY {
+		// Drop dead on hitting the floor. Give it a little upward motion to
+		// make the user see that it is dead.
+		g.ySpeed = -25
+		g.isAlive = false
+		g.deathCause = deathByFloor
+		g.sounds = append(g.sounds, "rsc/hit_floor.wav")
+		g.playDeathSoundIn = 60
+	}
+
+	for i := range g.gaps {
+		g.gaps[i].shakeTimer--
+	}
+
+	// Collide with the pipes.
+	if g.isAlive {
+		gopher := g.gopherSprite()
+		for i, gap := range g.gaps {
+			top := g.topPipeSprite(gap).solidBounds()
+			bottom := g.bottomPipeSprite(gap).solidBounds()
+			topCollides := pipeCollides(gopher, g.topPipeSprite(gap))
+			bottomCollides := pipeCollides(gopher, g.bottomPipeSprite(gap))
+			if topCollides || bottomCollides {
+				g.isAlive = false
+				g.deathCause = deathByTopPipe
+				if bottomCollides {
+					g.deathCause = deathByBottomPipe
+				}
+				g.sounds = append(g.sounds, "rsc/hit_pipe.wav")
+				g.playDeathSoundIn = 25
+				g.gaps[i].topPipeShaking = topCollides
+				g.gaps[i].bottomPipeShaking = bottomCollides
+				g.gaps[i].shakeTimer = pipeShakeFrameCount
+
+				// Break pieces off where the gopher hit the pipe.
+				hit := top
+				if bottomCollides {
+					hit = bottom
+				}
+				circle := gopherCollisionCircle(gopher)
+				hitX := min(hit.right, max(hit.left, circle.centerX))
+				hitY := min(hit.bottom, max(hit.top, circle.centerY))
+				tint := biomeAt(pipeAt(float64(gap.centerX))).pipeTint
+				g.particles.emitTinted(pipeEmitter, g.x+float64(hitX), float64(hitY), tint)
+			}
+		}
+	}
+
+	g.playDeathSoundIn--
+	if g.playDeathSoundIn == 0 {
+		g.sounds = append(g.sounds, "rsc/death.wav")
+	}
+
+	g.died = wasAlive && !g.isAlive
+
+	if g.scoreAnimationTime > 0 {
+		g.scoreAnimationTime = max(0, g.scoreAnimationTime-0.05)
+	}
+
+	g.weather.update(windowW, windowH, g.xSpeed)
+	g.particles.update()
+	// New background items come in on the right, so they belong to the biome
+	// that is coming up.
+	upcomingBiome := biomeAt(pipeAt(g.x + windowW))
+	for _, layer := range g.parallax {
+		layer.update(g, g.xSpeed, upcomingBiome.name)
+	}
+
+	g.nameAnimationTime++
+
+	if !g.isAlive && g.deceasedTextTime > 0 {
+		g.deceasedTextTime--
+	}
+
+	if restartable {
+		g.killScrollY--
+	}
+
+	g.steps++
+}
+
+// kill returns the record of the run for the kill history, it is called when
+// the gopher died.
+func (g *game) kill(name, nameSource string) kill {
+	return kill{
+		Name:        name,
+		NameSource:  nameSource,
+		Score:       g.score,
+		Accessories: slices.Clone(g.accessories),
+		Weather:     g.weather.kind.id,
+		Replay: &replay{
+			Seed:               g.seed,
+			Flaps:              slices.Clone(g.flaps),
+			AccessoryCollision: g.accessoryCollision,
+			Gaps:               g.gapPattern,
+		},
+	}
+}
+
+// Size and ImageSize make the game an imageSizer for the parallax layers, so
+// it places the background items without a window.
+func (g *game) Size() (width, height int) {
+	return windowW, windowH
+}
+
+func (g *game) ImageSize(path string) (width, height int, err error) {
+	if size, ok := g.imageSizes[path]; ok {
+		return size[0], size[1], nil
+	}
+	width, height, err = imageFileSize(assets, sourceImage(path))
+	if err != nil {
+		return 0, 0, err
+	}
+	if g.imageSizes == nil {
+		g.imageSizes = make(map[string][2]int)
+	}
+	g.imageSizes[path] = [2]int{width, height}
+	return width, height, nil
+}
+
+// pipeAt converts a world x coordinate to the number of the pipe at that
+// position, the first pipe is number 0.
+func pipeAt(worldX float64) float64 {
+	return (worldX - firstGapX) / gapDistX
+}
+
+// draw draws the world: the sky, the background, the pipes, the foreground and
+// the gopher. The texts on top are drawn by the caller. others are gophers
+// that fly through the same gaps, e.g. an opponent, they are drawn behind this
+// game's gopher.
+func (g *game) draw(window renderer, others ...*game) {
+	g.drawPanned(window, 0, g.parallax, others...)
+}
+
+// drawPanned draws the world with the camera moved panX pixels to the right
+// and with the given parallax layers, for the photo mode.
+func (g *game) drawPanned(window renderer, panX float64, layers []*parallaxLayer, others ...*game) {
+	cameraX := g.x + panX
+	skyTop, skyBottom, darkness := skyAt(pipeAt(cameraX + windowW/2))
+	drawSky(window, skyTop, skyBottom)
+	for _, layer := range layers {
+		if layer.Weather {
+			g.weather.draw(window)
+		} else if !layer.Foreground {
+			layer.draw(window, panX)
+		}
+	}
+	drawNight(window, darkness)
+	g.drawPipes(window, cameraX)
+	for _, layer := range layers {
+		if layer.Foreground {
+			layer.draw(window, panX)
+		}
+	}
+	for _, other := range others {
+		other.drawGopher(window, round(cameraX-other.x))
+	}
+	g.drawGopher(window, round(panX))
+	g.particles.draw(window, cameraX)
+}
+
+// drawPipes draws the pipes, tinted for their biomes and shaking after the
+// gopher hit them. cameraX is the world x at the left of the window.
+func (g *game) drawPipes(window renderer, cameraX float64) {
+	for _, gap := range g.gaps {
+		gapX := gap.centerX - g.pipeW/2 - round(cameraX)
+		pipe := tintedImage(pipeImage, biomeAt(pipeAt(float64(gap.centerX))).pipeTint)
+
+		rotation := 0
+		if gap.shakeTimer > 0 {
+			amplitude := 7 * float64(gap.shakeTimer) / pipeShakeFrameCount
+			t := float64(pipeShakeFrameCount - gap.shakeTimer)
+			rotation = round(math.Sin(t*0.8) * amplitude)
+		}
+
+		// Bottom pipe.
+		bottomRotation := 0
+		if gap.bottomPipeShaking && gap.shakeTimer > 0 {
+			bottomRotation = rotation
+		}
+		bottomY := gap.centerY + gapHeight/2
+		window.DrawImageFileRotated(pipe, gapX, bottomY, bottomRotation)
+
+		// Top pipe.
+		topRotation := 0
+		if gap.topPipeShaking && gap.shakeTimer > 0 {
+			topRotation = rotation
+		}
+		topY := gap.centerY - gapHeight/2 - g.pipeH
+		window.DrawImageFileRotated(pipe, gapX, topY, 180+topRotation)
+	}
+}
+
+// drawGopher draws the gopher with its tail and accessories, panX pixels left
+// of where it flies.
+func (g *game) drawGopher(window renderer, panX int) {
+	gopherX, gopherY := g.gopherXOffset+finalGopherX-panX, round(g.y)
+	gopherRotation := round(g.rotation)
+	window.DrawImageFileRotated(g.gopherImage(), gopherX, gopherY, gopherRotation)
+	window.DrawImageFileRotated(g.tailImage(), gopherX, gopherY, gopherRotation)
+	for _, a := range g.manifest.layers(g.accessories) {
+		dx, dy := rotateOffset(a.OffsetX, a.OffsetY, gopherRotation)
+		window.DrawImageFileRotated(a.Image, gopherX+dx, gopherY+dy, gopherRotation)
+	}
+}
+
+// drawName draws the animated name above the gopher's head.
+func (g *game) drawName(window renderer, name string) {
+	gopherW, _, _ := window.ImageSize(g.gopherImage())
+	headNameW, headNameH := window.GetScaledTextSize(name, headNameScale)
+	headNameX := g.gopherXOffset + finalGopherX + gopherW/2 - headNameW/2
+	headNameY := round(g.y) - headNameH
+	runeW, _ := window.GetScaledTextSize("x", headNameScale)
+	runeX := headNameX
+	runeI := 0
+	for _, r := range name {
+		yOffset := (math.Sin(0.5*float64(runeI)+0.075*float64(g.nameAnimationTime)) + 1) / 2
+		runeY := headNameY - round(yOffset*0.75*float64(headNameH))
+		window.DrawScaledText(string(r), runeX, runeY, headNameScale, draw.RGBA(0, 0, 0, g.nameAlpha))
+		runeX += runeW
+		runeI++
+	}
+}
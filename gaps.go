@@ -0,0 +1,367 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// gapGenerator places the gaps, one after the other. It gets the run's random
+// numbers and the center of the gap before and returns the center of the next
+// one. Patterns are listed in gapPatterns.
+type gapGenerator interface {
+	nextGapY(r *rand.Rand, previousY int) int
+}
+
+const (
+	// minGapY and maxGapY are the highest and lowest gap centers that leave
+	// enough of both pipes visible.
+	minGapY = gapHeight/2 + minVisiblePipeHeight
+	maxGapY = windowH - gapHeight/2 - minVisiblePipeHeight - 1
+
+	defaultGapPattern = "random"
+	// uncheckedGapPattern puts the gaps anywhere, even where the gopher cannot
+	// reach them. This is how all gaps were placed before the others existed,
+	// replays without a pattern use it.
+	uncheckedGapPattern = "unchecked"
+
+	// firstGapY is where the gap before the first one would be, the patterns
+	// start from there.
+	firstGapY = windowH / 2
+
+	stairHeight  = 60
+	zigZagSpread = 60
+	tunnelWiggle = 40
+)
+
+// gapPatterns are the ways to place the gaps. All but the unchecked pattern
+// are wrapped in solvableGaps.
+var gapPatterns = map[string]func() gapGenerator{
+	"random":            func() gapGenerator { return randomGaps{} },
+	"stairs":            func() gapGenerator { return &stairGaps{} },
+	"zigzag":            func() gapGenerator { return &zigZagGaps{} },
+	"tunnel":            func() gapGenerator { return tunnelGaps{} },
+	uncheckedGapPattern: func() gapGenerator { return randomGaps{} },
+}
+
+// gapPatternNames lists the gapPatterns for help texts.
+func gapPatternNames() []string {
+	var names []string
+	for name := range gapPatterns {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func clampGapY(y int) int {
+	return min(maxGapY, max(minGapY, y))
+}
+
+// randomGaps puts every gap anywhere, independent of the gap before.
+type randomGaps struct{}
+
+func (randomGaps) nextGapY(r *rand.Rand, previousY int) int {
+	return minGapY + r.Intn(maxGapY+1-minGapY)
+}
+
+// stairGaps go up or down by stairHeight every gap, turning around at the
+// top and the bottom.
+type stairGaps struct {
+	direction int
+}
+
+func (s *stairGaps) nextGapY(r *rand.Rand, previousY int) int {
+	if s.direction == 0 {
+		s.direction = 1 - 2*r.Intn(2)
+	}
+	y := previousY + s.direction*stairHeight
+	if y < minGapY || y > maxGapY {
+		s.direction = -s.direction
+		y = previousY + s.direction*stairHeight
+	}
+	return clampGapY(y)
+}
+
+// zigZagGaps alternate between the top and the bottom of the screen.
+type zigZagGaps struct {
+	low bool
+}
+
+func (z *zigZagGaps) nextGapY(r *rand.Rand, previousY int) int {
+	z.low = !z.low
+	if z.low {
+		return maxGapY - r.Intn(zigZagSpread)
+	}
+	return minGapY + r.Intn(zigZagSpread)
+}
+
+// tunnelGaps wind slowly, every gap is close to the one before, like a long
+// tunnel.
+type tunnelGaps struct{}
+
+func (tunnelGaps) nextGapY(r *rand.Rand, previousY int) int {
+	return clampGapY(previousY - tunnelWiggle + r.Intn(2*tunnelWiggle+1))
+}
+
+// solvableGaps only lets its pattern place gaps that the gopher can fly
+// through, after flying through all the gaps before. It keeps the states that
+// the gopher can be in behind the last gap. A gap that cannot be reached is
+// moved towards the gap before, until it can be.
+type solvableGaps struct {
+	pattern gapGenerator
+	flight  *flightModel
+	wind    *gusts
+	states  *flightStates
+	centerX int
+}
+
+// newSolvableGaps starts with the gopher's left at x in the given step, at y
+// with the given speed, before the first gap. The gaps are placed for the
+// run's wind.
+func newSolvableGaps(pattern gapGenerator, flight *flightModel, wind *gusts, x, step int, y, ySpeed float64) *solvableGaps {
+	return &solvableGaps{
+		pattern: pattern,
+		flight:  flight,
+		wind:    wind,
+		states:  flight.startStates(x, step, y, ySpeed),
+		centerX: firstGapX,
+	}
+}
+
+func (s *solvableGaps) nextGapY(r *rand.Rand, previousY int) int {
+	wanted := s.pattern.nextGapY(r, previousY)
+	// The flight up to the pipes is the same for every gap.
+	passed := s.states
+	s.states = s.flight.approach(passed, s.wind, s.centerX)
+	y, ok := s.closestGap(wanted, previousY)
+	if !ok {
+		// Gusts can carry the gopher where it cannot get through any gap.
+		// Then the wind calms down behind the gap before.
+		s.wind.calm(passed.step)
+		s.states = s.flight.approach(passed, s.wind, s.centerX)
+		y, ok = s.closestGap(wanted, previousY)
+	}
+	if ok {
+		s.states = s.flight.pass(s.states, s.wind, s.centerX, y)
+	} else {
+		// This does not happen with the game's constants. If it does, the
+		// run cannot be solved anyway, so we go on from behind the gap with
+		// every state possible.
+		y = wanted
+		s.states = s.flight.pass(s.flight.allStates(s.states.x, s.states.step), s.wind, s.centerX, y)
+	}
+	s.centerX += gapDistX
+	return y
+}
+
+// closestGap finds a gap close to the wanted one that the gopher can fly
+// through.
+func (s *solvableGaps) closestGap(wanted, previousY int) (int, bool) {
+	if s.reachable(wanted) {
+		return wanted, true
+	}
+	if s.reachable(previousY) {
+		// Find the farthest reachable gap on the way from the previous gap
+		// to the wanted one.
+		reachable, unreachable := previousY, wanted
+		for abs(unreachable-reachable) > 1 {
+			mid := (reachable + unreachable) / 2
+			if s.reachable(mid) {
+				reachable = mid
+			} else {
+				unreachable = mid
+			}
+		}
+		return reachable, true
+	}
+	for _, y := range gapCandidates(wanted) {
+		if s.reachable(y) {
+			return y, true
+		}
+	}
+	return 0, false
+}
+
+// reachable reports whether the gopher can fly through the next gap at y.
+func (s *solvableGaps) reachable(y int) bool {
+	return len(s.flight.pass(s.states, s.wind, s.centerX, y).states) > 0
+}
+
+// gapSearchStep is the distance between the gaps that gapCandidates returns.
+const gapSearchStep = 10
+
+// gapCandidates returns gap heights, starting with y and going further away.
+func gapCandidates(y int) []int {
+	candidates := []int{y}
+	for distance := gapSearchStep; distance <= maxGapY-minGapY; distance += gapSearchStep {
+		for _, c := range []int{y - distance, y + distance} {
+			if minGapY <= c && c <= maxGapY {
+				candidates = append(candidates, c)
+			}
+		}
+	}
+	return candidates
+}
+
+// flightMaxYSpeed is the fastest fall that is followed, faster gophers crash
+// into the floor anyway.
+const flightMaxYSpeed = 30
+
+// flightRotations are the smallest and largest rotations that the gopher can
+// have. It turns towards its speed, which goes from the speed right after a
+// flap in an upwards gust to flightMaxYSpeed, see game.step.
+func flightRotations() (minRotation, maxRotation int) {
+	return int(math.Floor(rotationPerYSpeed * (clickYSpeed + gravity - maxGustForce))),
+		int(math.Ceil(rotationPerYSpeed * flightMaxYSpeed))
+}
+
+// flightModel is a simple version of the gopher to check that gaps can be
+// flown through. The gopher is a box around its solid pixels in all of its
+// rotations. It moves exactly like in game.step, including the wind, but the
+// model knows nothing of accessories, so the gaps are only guaranteed to be
+// solvable if accessories do not collide.
+type flightModel struct {
+	// left, top, right and bottom are the edges of the gopher's box, relative
+	// to the gopher image's top left. Like all rectangles, the right and bottom
+	// edges are just outside.
+	left, top, right, bottom int
+	// pipeLeft and pipeRight are the edges of a pipe's solid pixels, relative
+	// to its gap's center.
+	pipeLeft, pipeRight int
+	// seen marks the cells that step has filled with the current stamp.
+	seen  []uint32
+	stamp uint32
+}
+
+func newFlightModel(gopher, pipe rectangle, pipeW int) *flightModel {
+	return &flightModel{
+		left:      gopher.left,
+		top:       gopher.top,
+		right:     gopher.right,
+		bottom:    gopher.bottom,
+		pipeLeft:  pipe.left - pipeW/2,
+		pipeRight: pipe.right - pipeW/2,
+		seen:      make([]uint32, flightSpeeds*flightHeights),
+	}
+}
+
+// flightStates are the heights and speeds that the gopher can have when its
+// left is at x, after the given number of steps.
+//
+// Every state is one that the gopher can really be in, computed like in
+// game.step. Many flap sequences lead to nearly the same state, so of all
+// states in a cell of flightCellHeight pixels and flightCellSpeed pixels per
+// step, only the first is kept. The model might miss a way through a gap
+// because of that, but every way that it finds is real.
+type flightStates struct {
+	x, step int
+	states  []flightState
+}
+
+type flightState struct {
+	y, ySpeed float64
+}
+
+const (
+	flightCellHeight = 2
+	flightCellSpeed  = 1
+	// The slowest speed is right after a flap in an upwards gust.
+	flightMinYSpeed = clickYSpeed + gravity - maxGustForce
+	flightSpeeds    = int(flightMaxYSpeed-clickYSpeed)/flightCellSpeed + 1
+	flightHeights   = (floorY-ceilingY)/flightCellHeight + 1
+)
+
+// flightCell returns the index of the state's cell in flightModel.seen. It
+// reports false for states that are out of the followed speeds. Their height
+// must be between the ceiling and the floor.
+func flightCell(state flightState) (int, bool) {
+	if state.ySpeed > flightMaxYSpeed {
+		return 0, false
+	}
+	speed := int((state.ySpeed - flightMinYSpeed) / flightCellSpeed)
+	height := int((state.y - ceilingY) / flightCellHeight)
+	return speed*flightHeights + height, true
+}
+
+// alive reports whether the state does not hit the ceiling or the floor.
+func (state flightState) alive() bool {
+	return ceilingY < state.y && state.y < floorY
+}
+
+func (f *flightModel) startStates(x, step int, y, ySpeed float64) *flightStates {
+	s := &flightStates{x: x, step: step}
+	if start := (flightState{y: y, ySpeed: ySpeed}); start.alive() {
+		s.states = append(s.states, start)
+	}
+	return s
+}
+
+func (f *flightModel) allStates(x, step int) *flightStates {
+	s := &flightStates{x: x, step: step}
+	for ySpeed := flightMinYSpeed; ySpeed <= flightMaxYSpeed; ySpeed += flightCellSpeed {
+		for y := ceilingY + flightCellHeight; y < floorY; y += flightCellHeight {
+			s.states = append(s.states, flightState{y: float64(y), ySpeed: ySpeed})
+		}
+	}
+	return s
+}
+
+// approach flies the gopher up to the pipes of the gap at centerX, where they
+// do not limit its states yet.
+func (f *flightModel) approach(states *flightStates, wind *gusts, centerX int) *flightStates {
+	pipeLeft := centerX + f.pipeLeft
+	for states.x+gopherSpeed+f.right <= pipeLeft && len(states.states) > 0 {
+		states = f.step(states, wind.at(states.step).force())
+	}
+	return states
+}
+
+// pass flies the gopher through the gap with the given center and returns the
+// states that it can be in once it is past the pipes. The states are empty if
+// it cannot get through.
+func (f *flightModel) pass(states *flightStates, wind *gusts, centerX, centerY int) *flightStates {
+	states = f.approach(states, wind, centerX)
+	pipeRight := centerX + f.pipeRight
+	minY := float64(centerY - gapHeight/2 - f.top)
+	maxY := float64(centerY + gapHeight/2 - f.bottom)
+	for states.x+f.left < pipeRight && len(states.states) > 0 {
+		states = f.step(states, wind.at(states.step).force())
+		// Keep only the states that do not hit the pipes.
+		states.states = slices.DeleteFunc(states.states, func(state flightState) bool {
+			return state.y < minY || state.y > maxY
+		})
+	}
+	return states
+}
+
+// step moves the states by one game step, with and without flapping. Like in
+// game.step, a flap sets the speed, then the gopher moves with its speed and
+// gravity and the wind's force change the speed. States that hit the ceiling
+// or the floor are dropped.
+func (f *flightModel) step(states *flightStates, force float64) *flightStates {
+	next := &flightStates{x: states.x + gopherSpeed, step: states.step + 1}
+	f.stamp++
+	if f.stamp == 0 {
+		clear(f.seen)
+		f.stamp = 1
+	}
+	add := func(state flightState) {
+		state.ySpeed += gravity
+		state.ySpeed += force
+		if !state.alive() {
+			return
+		}
+		cell, ok := flightCell(state)
+		if !ok || f.seen[cell] == f.stamp {
+			return
+		}
+		f.seen[cell] = f.stamp
+		next.states = append(next.states, state)
+	}
+	for _, state := range states.states {
+		add(flightState{y: state.y + state.ySpeed, ySpeed: state.ySpeed})
+		add(flightState{y: state.y + clickYSpeed, ySpeed: clickYSpeed})
+	}
+	return next
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFlightModelContainsTheGopher(t *testing.T) {
+	g := newTestGame(t)
+	f := g.flight
+	minRotation, maxRotation := flightRotations()
+	for _, frame := range animationFrames {
+		for _, tail := range []string{tailDownImage, tailCenterImage, tailUpImage} {
+			mask := gopherMask([]maskLayer{{image: frame}, {image: tail}})
+			for rotation := minRotation; rotation <= maxRotation; rotation++ {
+				gopher := sprite{mask: mask, w: g.gopherW, h: g.gopherH, rotation: rotation}
+				lookup := gopher.lookup()
+				for y := -g.gopherH; y < 2*g.gopherH; y++ {
+					for x := -g.gopherW; x < 2*g.gopherW; x++ {
+						if lookup(x, y) && (x < f.left || x >= f.right || y < f.top || y >= f.bottom) {
+							t.Fatalf("%s with %s rotated by %d: pixel %d,%d is outside of the flight model's box %d,%d,%d,%d",
+								frame, tail, rotation, x, y, f.left, f.top, f.right, f.bottom)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFlightRotationsCoverTheGame(t *testing.T) {
+	g := newTestGame(t)
+	g.reset(1, nil)
+	minRotation, maxRotation := flightRotations()
+	// The gopher flaps whenever it gets low, until it hits a pipe.
+	for g.isAlive && g.steps < 5000 {
+		g.step(g.ySpeed > 0 && g.y > windowH/2)
+		if rotation := round(g.rotation); rotation < minRotation || rotation > maxRotation {
+			t.Fatalf("the gopher is rotated by %d at speed %v, the flight model covers %d to %d",
+				rotation, g.ySpeed, minRotation, maxRotation)
+		}
+	}
+}
+
+// flightPath finds flaps that take the flight model through the gaps, one per
+// step from the start states. It reports false if there are none.
+func flightPath(f *flightModel, wind *gusts, start *flightStates, gapYs []int) ([]bool, bool) {
+	// history has the states after every step, like in flightModel.pass.
+	history := []*flightStates{start}
+	for i, centerY := range gapYs {
+		centerX := firstGapX + i*gapDistX
+		pipeLeft, pipeRight := centerX+f.pipeLeft, centerX+f.pipeRight
+		for states := history[len(history)-1]; states.x+f.left < pipeRight; {
+			states = f.step(states, wind.at(states.step).force())
+			if states.x+f.right > pipeLeft {
+				minY := float64(centerY - gapHeight/2 - f.top)
+				maxY := float64(centerY + gapHeight/2 - f.bottom)
+				states.states = slices.DeleteFunc(states.states, func(state flightState) bool {
+					return state.y < minY || state.y > maxY
+				})
+			}
+			if len(states.states) == 0 {
+				return nil, false
+			}
+			history = append(history, states)
+		}
+	}
+
+	// Go back from any state behind the last gap. Every state was computed
+	// from a state in the step before, so one of them gives exactly its height
+	// and speed.
+	state := history[len(history)-1].states[0]
+	flaps := make([]bool, len(history)-1)
+	for step := len(history) - 1; step > 0; step-- {
+		force := wind.at(history[step-1].step).force()
+		found := false
+		for _, before := range history[step-1].states {
+			for _, flap := range []bool{false, true} {
+				next := before
+				if flap {
+					next.ySpeed = clickYSpeed
+				}
+				next.y += next.ySpeed
+				next.ySpeed += gravity
+				next.ySpeed += force
+				if next == state {
+					state, flaps[step-1], found = before, flap, true
+					break
+				}
+			}
+			if found {
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return flaps, true
+}
+
+func TestSolvableGapsCanBeFlown(t *testing.T) {
+	const (
+		gapCount = 30
+		// Every pattern is flown in a few runs with wind and without.
+		runsPerWeather = 2
+	)
+	for _, pattern := range []string{"random", "stairs", "zigzag", "tunnel"} {
+		t.Run(pattern, func(t *testing.T) {
+			g := newTestGame(t)
+			g.parallax = nil
+			g.gapPattern = pattern
+			runs := make(map[bool]int)
+			for seed := int64(1); runs[false] < runsPerWeather || runs[true] < runsPerWeather; seed++ {
+				g.reset(seed, nil)
+				wind := g.weather.kind.wind
+				if runs[wind] == runsPerWeather {
+					continue
+				}
+				runs[wind]++
+
+				// The gaps are placed while the gopher flies, so they are
+				// taken from a run before.
+				start := g.flight.startStates(round(g.x)+g.gopherXOffset+finalGopherX, g.steps, g.y, g.ySpeed)
+				var gapYs []int
+				for _, gap := range g.gaps {
+					gapYs = append(gapYs, gap.centerY)
+				}
+				for len(gapYs) < gapCount {
+					gapYs = append(gapYs, g.nextGapY())
+				}
+				flaps, ok := flightPath(g.flight, g.weather.gusts, start, gapYs)
+				if !ok {
+					t.Fatalf("seed %d: the flight model finds no way through the gaps %v in the %s", seed, gapYs, g.weather.kind.id)
+				}
+
+				g.reset(seed, nil)
+				for _, flap := range flaps {
+					g.step(flap)
+					if !g.isAlive {
+						t.Fatalf("seed %d: the gopher died of %q with score %d in the %s, following the flight model through the gaps %v",
+							seed, g.deathCause, g.score, g.weather.kind.id, gapYs)
+					}
+				}
+				if g.score < gapCount-1 {
+					t.Errorf("seed %d: the gopher only scored %d of %d gaps", seed, g.score, gapCount)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// backgroundColor is behind the texts of the HUD and behind the menus.
+var backgroundColor = rgb(151, 255, 255)
+
+// hud is what the texts around the game show, see drawHUD.
+type hud struct {
+	score int
+	// scoreAnimationTime grows the score for a moment after each pipe.
+	scoreAnimationTime float64
+	highscore          int
+	killCount          int
+	name               string
+	// nameAlpha is the opacity of the name above the gopher's head. The name
+	// in the corner fades in while that one fades out.
+	nameAlpha float32
+	// playingAlpha blends from "now playing" to "recently deceased" as it goes
+	// from 1 to 0.
+	playingAlpha    float32
+	backgroundColor draw.Color
+}
+
+// drawHUD draws the highscore in the top right corner, the gopher's name and
+// the kill count in the bottom right corner and the score at the top. It
+// returns the score text's height.
+func drawHUD(window renderer, h hud) (scoreHeight int) {
+	windowW, windowH := window.Size()
+
+	textBackgroundColor := h.backgroundColor
+	textBackgroundColor.A = 0.6
+	const textBorderSize = 5
+
+	const highscoreScale = 4
+	highscoreText := " " + tr(msgHighscore, h.highscore) + " "
+	highscoreW, highscoreH := window.GetScaledTextSize(highscoreText, highscoreScale)
+	highscoreX := windowW - highscoreW
+	highscoreYMargin := 10
+	highscoreY := highscoreYMargin
+	highscoreBottom := highscoreY + highscoreH + highscoreYMargin
+	// Fill the text background.
+	window.FillRect(highscoreX, 0, windowW-highscoreX, highscoreBottom, textBackgroundColor)
+	// Create a fuzzy border around the text background.
+	textBorderColor := textBackgroundColor
+	for i := range textBorderSize - 1 {
+		textBorderColor.A -= textBackgroundColor.A / float32(textBorderSize)
+		window.FillRect(highscoreX-1-i, 0, 1, highscoreBottom+i, textBorderColor)
+		window.FillRect(highscoreX-1-i, highscoreBottom+i, windowW-highscoreX+1+i, 1, textBorderColor)
+	}
+	// Draw the text on top of the background.
+	window.DrawScaledText(highscoreText, highscoreX, highscoreY, highscoreScale, draw.Black)
+
+	// We now draw the gopher name and the kill count in the bottom right
+	// hand corner. We want to surround both of these with a single text
+	// background rectangle. That is why we do the text size and position
+	// calculations first, then draw the background, then draw the texts on
+	// top of it.
+	const killTextYMargin = 10
+	const killScale = 2
+	killText := " " + trn(msgDeadGophers, h.killCount, h.killCount) + " "
+	killW, killH := window.GetScaledTextSize(killText, killScale)
+	killX := windowW - killW
+	killY := windowH - killH - killTextYMargin
+
+	// Render the name above the kill count.
+	// We blend the text "now playing ..." and "recently deceased ..." when
+	// the gopher goes from alive to dead. That is why we render both texts
+	// always, but with a different opacity.
+	const nameScale = 2
+
+	aliveNameText := " " + tr(msgNowPlaying, h.name) + " "
+	aliveNameW, aliveNameH := window.GetScaledTextSize(aliveNameText, nameScale)
+	aliveNameX := windowW - aliveNameW
+	aliveNameY := killY - aliveNameH
+	aliveNameAlpha := (1 - h.nameAlpha) * h.playingAlpha
+
+	deadNameText := " " + tr(msgRecentlyDeceased, h.name) + " "
+	deadNameW, deadNameH := window.GetScaledTextSize(deadNameText, nameScale)
+	deadNameX := windowW - deadNameW
+	deadNameY := killY - deadNameH
+	deadNameAlpha := (1 - h.nameAlpha) * (1 - h.playingAlpha)
+
+	// Create the text background.
+	backX := killX
+	if aliveNameAlpha > 0 && aliveNameX < backX {
+		backX = aliveNameX
+	}
+	if deadNameAlpha > 0 && deadNameX < backX {
+		backX = deadNameX
+	}
+	backY := killY - killTextYMargin
+	if aliveNameAlpha > 0 || deadNameAlpha > 0 {
+		backY = deadNameY - killTextYMargin
+	}
+	// Fill the text background.
+	window.FillRect(backX, backY, windowW-backX, windowH-backY, textBackgroundColor)
+	// Create a fuzzy border around the text background.
+	textBorderColor = textBackgroundColor
+	for i := range textBorderSize - 1 {
+		textBorderColor.A -= textBackgroundColor.A / float32(textBorderSize)
+		window.FillRect(backX-1-i, backY-i, 1, windowH-backY+i, textBorderColor)
+		window.FillRect(backX-1-i, backY-1-i, windowW-backX+1+i, 1, textBorderColor)
+	}
+
+	// Write the texts on top of the background.
+	window.DrawScaledText(aliveNameText, aliveNameX, aliveNameY, nameScale, draw.RGBA(0, 0, 0, aliveNameAlpha))
+	window.DrawScaledText(deadNameText, deadNameX, deadNameY, nameScale, draw.RGBA(0, 0, 0, deadNameAlpha))
+	window.DrawScaledText(killText, killX, killY, killScale, draw.RGB(0.7, 0, 0))
+
+	const (
+		regularScoreScale = 7.0
+		maxScoreScale     = 12.0
+	)
+	scoreScale := float32(regularScoreScale)
+	if h.scoreAnimationTime > 0 {
+		scoreArc := (math.Sin(1.5*math.Pi+2*math.Pi*h.scoreAnimationTime) + 1) * 0.5
+		scoreScale = float32(regularScoreScale + scoreArc*(maxScoreScale-regularScoreScale))
+	}
+	scoreText := fmt.Sprintf(" %d ", h.score)
+	scoreW, scoreH := window.GetScaledTextSize(scoreText, scoreScale)
+	scoreX := (windowW - scoreW) / 2
+	window.DrawScaledText(scoreText, scoreX, 0, scoreScale, draw.Black)
+	return scoreH
+}
+
+// memorialCaptionHeight is the space above and below the list of heroes.
+const memorialCaptionHeight = 180
+
+// drawMemorial draws the list of all dead gophers, newest first, with a
+// eulogy for each. The list starts below the window and scrolls up with
+// negative scrollY. It returns the y below the list, where the statistics
+// go, and the list's width.
+func drawMemorial(window renderer, m *accessoryManifest, kills []kill, highscore, scrollY int) (bottom, width int) {
+	windowW, windowH := window.Size()
+
+	longestNameCharCount := 0
+	for _, k := range kills {
+		longestNameCharCount = max(longestNameCharCount, utf8.RuneCountInString(k.Name))
+	}
+
+	const textScale = 2.5
+
+	longestEulogyW := 0
+	for i := range eulogyCount() {
+		// Plural forms can have different lengths, so we try all.
+		for _, n := range append(pluralSamples[:len(pluralSamples):len(pluralSamples)], highscore) {
+			text := eulogy(i, strings.Repeat("A", longestNameCharCount), n)
+			textW, _ := window.GetScaledTextSize(text, textScale)
+			longestEulogyW = max(longestEulogyW, textW)
+		}
+	}
+
+	gopherW, gopherH, _ := window.ImageSize(deadFrame)
+	const gopherScale = 0.33
+	gopherW = round(float64(gopherW) * gopherScale)
+	gopherH = round(float64(gopherH) * gopherScale)
+
+	backgroundW := 4*gopherW + longestEulogyW
+
+	alphaAtY := func(y int) float32 {
+		dy := abs(windowH/2 - y)
+		alpha := float32(dy-40) / 90
+		return max(0.1, min(0.95, alpha))
+	}
+
+	y := windowH + 280 + scrollY
+
+	const captionScale = 4
+
+	writeCaption := func(caption string, top, bottom int) {
+		captionW, captionH := window.GetScaledTextSize(caption, captionScale)
+		captionY := (top + bottom - captionH) / 2
+		captionAlpha := alphaAtY(captionY + captionH/2)
+		topAlpha := alphaAtY(top)
+		bottomAlpha := alphaAtY(bottom)
+		if topAlpha == bottomAlpha {
+			window.FillRect((windowW-backgroundW)/2, top, backgroundW, bottom-top+1, draw.RGBA(1, 1, 1, topAlpha))
+		} else {
+			for y := top; y <= bottom; y++ {
+				a := alphaAtY(y)
+				window.FillRect((windowW-backgroundW)/2, y, backgroundW, 1, draw.RGBA(1, 1, 1, a))
+			}
+		}
+		window.DrawScaledText(caption, (windowW-captionW)/2, captionY, captionScale, draw.RGBA(0.5, 0, 0, captionAlpha))
+	}
+
+	caption := trn(msgInHonorOfOurHeroes, len(kills))
+	writeCaption(caption, y-memorialCaptionHeight, y-1)
+
+	for i := len(kills) - 1; i >= 0; i-- {
+		if -gopherH < y && y < windowH {
+			kill := kills[i]
+
+			lineCenterY := y + gopherH/2
+
+			alpha := alphaAtY(lineCenterY)
+
+			top := y
+			bottom := y + gopherH - 1
+			topAlpha := alphaAtY(top)
+			bottomAlpha := alphaAtY(bottom)
+
+			if topAlpha == bottomAlpha {
+				window.FillRect((windowW-backgroundW)/2, y, backgroundW, gopherH, draw.RGBA(1, 1, 1, alpha))
+			} else {
+				for y := top; y <= bottom; y++ {
+					a := alphaAtY(y)
+					window.FillRect((windowW-backgroundW)/2, y, backgroundW, 1, draw.RGBA(1, 1, 1, a))
+				}
+			}
+
+			text := eulogy(i, kill.Name, kill.Score)
+
+			textW, textH := window.GetScaledTextSize(text, textScale)
+			textOffsetY := (gopherH - textH) / 2
+
+			leftX := (windowW - 2*gopherW - textW) / 2
+
+			drawGopherAtX := func(x, xScale int) {
+				window.DrawImageFileTo(deadFrame, x, y, gopherW*xScale, gopherH, 0)
+				for _, a := range m.layers(kill.Accessories) {
+					dx := round(float64(a.OffsetX*xScale) * gopherScale)
+					dy := round(float64(a.OffsetY) * gopherScale)
+					window.DrawImageFileTo(a.Image, x+dx, y+dy, gopherW*xScale, gopherH, 0)
+				}
+				window.DrawImageFileTo(tailDownImage, x, y, gopherW*xScale, gopherH, 0)
+			}
+
+			drawGopherAtX(leftX, 1)
+
+			// Draw the hero's name.
+			textX := leftX + gopherW*3/2
+			textY := y + textOffsetY
+			window.DrawScaledText(text, textX, textY, textScale, draw.RGBA(0.5, 0, 0, alpha))
+
+			drawGopherAtX(textX+textW+gopherW+gopherW/2, -1)
+		}
+
+		y += gopherH
+	}
+
+	writeCaption(tr(msgYouWillBeMissed), y, y+memorialCaptionHeight)
+
+	return y + memorialCaptionHeight, backgroundW
+}
+
+// drawStatistics draws a bar for every run's score, colored by its weather. The
+// graph is at most at top and at least in the lower half of the window. It
+// needs two runs to be drawn.
+func drawStatistics(window renderer, kills []kill, highscore, top, width int) {
+	windowW, windowH := window.Size()
+	minGraphY := windowH/2 + 80
+	statisticsY := max(minGraphY, top)
+	if len(kills) < 2 || statisticsY >= windowH {
+		return
+	}
+
+	graphBackColor := draw.RGBA(1, 1, 1, 0.9)
+	graphForeColor := draw.RGBA(0, 0, 0, 0.9)
+	graphX := (windowW - width) / 2
+	graphY := statisticsY
+	graphW := width
+	graphH := windowH - 20 - minGraphY
+	const graphMarginTop = 120
+	const graphMarginBottom = 20
+	const graphMarginLeft = 30
+	const graphMarginRight = 30
+	zeroY := graphY + graphH - graphMarginBottom
+	highestX := 0
+	highestY := 0
+	highestName := ""
+
+	leftX := windowW/2 - 10*len(kills)
+	rightX := windowW/2 + 10*len(kills)
+	leftX = max(leftX, graphX+graphMarginLeft+1)
+	rightX = min(rightX, graphX+graphW-graphMarginRight-1)
+
+	window.FillRect(graphX, graphY, graphW, graphH, graphBackColor)
+
+	const captionScale = 2.5
+	caption := tr(msgStatistics)
+	captionW, _ := window.GetScaledTextSize(caption, captionScale)
+	captionX := graphX + (graphW-captionW)/2
+	captionY := graphY + 5
+	window.DrawScaledText(caption, captionX, captionY, captionScale, graphForeColor)
+	_, captionH := window.GetScaledTextSize(caption, captionScale)
+	legendY := drawWeatherLegend(
+		window,
+		kills,
+		graphX+graphMarginLeft,
+		captionY+captionH+10,
+		graphW-graphMarginLeft-graphMarginRight,
+	)
+	legendY = drawNameSourceLegend(
+		window,
+		kills,
+		graphX+graphMarginLeft,
+		legendY,
+		graphW-graphMarginLeft-graphMarginRight,
+	)
+	// The bars start below the legends, with room for the highscore's text.
+	const highscoreTextRoom = 40
+	innerGraphH := zeroY - max(graphY+graphMarginTop, legendY+highscoreTextRoom)
+
+	for i, k := range kills {
+		x := leftX + round(float64(i)/float64(len(kills)-1)*float64(rightX-leftX-1))
+
+		y := graphY + graphH - graphMarginBottom
+		if highscore > 0 {
+			y -= round(float64(k.Score) * float64(innerGraphH) / float64(highscore))
+		}
+
+		window.FillRect(x-1, y-1, 3, zeroY-y+1, k.weather().color)
+
+		if k.Score == highscore {
+			highestName = k.Name
+			highestX, highestY = x, y
+		}
+	}
+
+	window.FillRect(leftX-1, zeroY, rightX-leftX+2, 1, graphForeColor)
+
+	const textScale = 1.5
+
+	text := trn(msgClearedPipes, highscore, highestName, highscore)
+	textW, textH := window.GetScaledTextSize(text, textScale)
+	textX := highestX - textW/2
+	textY := highestY - textH - 10
+	textX = max(textX, graphX+graphMarginLeft)
+	textX = min(textX, graphX+graphW-graphMarginRight-textW)
+	window.DrawScaledText(text, textX, textY, textScale, graphForeColor)
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the golden images in testdata instead of comparing with them")
+
+// testKills are a fixed kill history for the screens that show it.
+var testKills = []kill{
+	{Name: "Gordon", NameSource: "en", Score: 3, Weather: "clear"},
+	{Name: "Ada", NameSource: "languages", Score: 12, Weather: "rain", Accessories: []string{"hat"}},
+	{Name: "Linus", NameSource: "en", Score: 0, Weather: "snow"},
+	{Name: "Grace", NameSource: "en", Score: 27, Weather: "storm", Accessories: []string{"round_glasses", "bowtie"}},
+	{Name: "Ken", NameSource: customNameSource, Score: 1, Weather: "blizzard"},
+	{Name: "Rob", NameSource: "gophers", Score: 8},
+}
+
+func TestScreensMatchGoldenImages(t *testing.T) {
+	defer setLanguage(currentLanguage.id)
+	setLanguage("en")
+	const highscore = 27
+
+	tests := []struct {
+		name string
+		draw func(r renderer)
+	}{
+		{"hud", func(r renderer) {
+			drawHUD(r, hud{
+				score:              14,
+				scoreAnimationTime: 0.5,
+				highscore:          highscore,
+				killCount:          len(testKills),
+				name:               "Gopher",
+				nameAlpha:          0.25,
+				playingAlpha:       1,
+				backgroundColor:    backgroundColor,
+			})
+		}},
+		{"hud_deceased", func(r renderer) {
+			drawHUD(r, hud{
+				score:           3,
+				highscore:       highscore,
+				killCount:       len(testKills),
+				name:            "Rob",
+				backgroundColor: backgroundColor,
+			})
+		}},
+		{"memorial", func(r renderer) {
+			drawMemorial(r, defaultAccessoryManifest(), testKills, highscore, -windowH)
+		}},
+		{"statistics", func(r renderer) {
+			// The graph is as wide as the memorial above it.
+			_, width := drawMemorial(newSoftwareRenderer(windowW, windowH), defaultAccessoryManifest(), testKills, highscore, 0)
+			drawStatistics(r, testKills, highscore, windowH/2, width)
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := newSoftwareRenderer(windowW, windowH)
+			r.FillRect(0, 0, windowW, windowH, backgroundColor)
+			test.draw(r)
+			compareGoldenImage(t, test.name, r.image)
+		})
+	}
+}
+
+// goldenTolerance is how far a color channel can be off before the pixel counts
+// as different. Floating point math can round differently on other machines.
+// A few pixels, like the ends of lines, may differ completely.
+const (
+	goldenTolerance     = 8
+	goldenPixelsPerDiff = 10000
+)
+
+// compareGoldenImage compares the image with testdata/<name>.png. With -update,
+// it writes the image there instead.
+func compareGoldenImage(t *testing.T, name string, have *image.RGBA) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".png")
+	if *update {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, have); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.MkdirAll("testdata", 0777); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, buf.Bytes(), 0666); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v, run the tests with -update to create it", err)
+	}
+	want, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want.Bounds() != have.Bounds() {
+		t.Fatalf("the image is %v but %s is %v", have.Bounds(), path, want.Bounds())
+	}
+
+	b := have.Bounds()
+	different := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			r1, g1, b1, a1 := have.At(x, y).RGBA()
+			r2, g2, b2, a2 := want.At(x, y).RGBA()
+			for _, d := range []int{
+				int(r1>>8) - int(r2>>8),
+				int(g1>>8) - int(g2>>8),
+				int(b1>>8) - int(b2>>8),
+				int(a1>>8) - int(a2>>8),
+			} {
+				if abs(d) > goldenTolerance {
+					different++
+					break
+				}
+			}
+		}
+	}
+	if different > b.Dx()*b.Dy()/goldenPixelsPerDiff {
+		t.Errorf("%d pixels differ from %s, run the tests with -update if the change is intended", different, path)
+	}
+}
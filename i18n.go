@@ -0,0 +1,654 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// messageID identifies a text that is shown to the player. Every language has
+// its own text for it, see the language catalogs below.
+type messageID int
+
+const (
+	msgLoading messageID = iota
+	msgHighscore
+	msgDeadGophers
+	msgNowPlaying
+	msgRecentlyDeceased
+	msgClickToRestart
+	msgPressToRestart
+	msgSetUpControls
+	msgEulogy1
+	msgEulogy2
+	msgEulogy3
+	msgEulogy4
+	msgEulogy5
+	msgEulogy6
+	msgInHonorOfOurHeroes
+	msgYouWillBeMissed
+	msgStatistics
+	msgClearedPipes
+	msgSettings
+	msgSettingsHelp
+	msgCaptureHelp
+	msgPressAKey
+	msgKeepOneBinding
+	msgResetControls
+	msgActionFlap
+	msgActionSettings
+	msgActionName
+	msgActionQuit
+	msgActionWardrobe
+	msgActionFullscreen
+	msgActionDebug
+	msgActionScreenshot
+	msgActionPhoto
+	msgOptionNames
+	msgOptionLanguage
+	msgLanguageIncomplete
+	msgNameYourGopher
+	msgNameHelp
+	msgNameTooLong
+	msgNameTooWide
+	msgNameCannotBeDrawn
+	msgNamesEnglish
+	msgNamesGerman
+	msgNamesSpanish
+	msgNamesJapanese
+	msgNamesGophers
+	msgNamesLanguages
+	msgNamesFile
+	msgNamesCustom
+	msgAccessoryUnlocked
+	msgWardrobe
+	msgWardrobeHelp
+	msgWearRandom
+	msgWearNothing
+	msgLocked
+	msgUnlockPipes
+	msgUnlockTotalPipes
+	msgUnlockKills
+	msgOptionPack
+	msgNoPack
+	msgAccessoryCollision
+	msgOn
+	msgOff
+	msgAfterRestart
+	msgPackNotLoaded
+	msgAccessoriesFailed
+	msgWeatherClear
+	msgWeatherRain
+	msgWeatherSnow
+	msgWeatherStorm
+	msgWeatherBlizzard
+	msgWeatherRuns
+	msgNamesLegend
+	msgLoadingFailed
+	msgPressEscapeToQuit
+	msgScreenshotSaved
+	msgScreenshotFailed
+	msgPhotoModeHelp
+	msgOpponentScore
+
+	// NOTE messageCount has to come last.
+	messageCount
+)
+
+// eulogies are written on the memorial wall, they take the gopher's name and
+// the number of pipes it cleared.
+var eulogies = []messageID{
+	msgEulogy1,
+	msgEulogy2,
+	msgEulogy3,
+	msgEulogy4,
+	msgEulogy5,
+	msgEulogy6,
+}
+
+// eulogyCount is the number of different eulogies, see eulogy.
+func eulogyCount() int {
+	if e, _ := currentPack.packEulogies(); len(e) > 0 {
+		return len(e)
+	}
+	return len(eulogies)
+}
+
+// eulogy returns the i'th eulogy for a gopher, starting over after the last
+// one. Asset packs can replace our eulogies.
+func eulogy(i int, name string, pipes int) string {
+	if e, plural := currentPack.packEulogies(); len(e) > 0 {
+		m := e[i%len(e)]
+		s := m[plural(pipes)]
+		if s == "" {
+			s = m[pluralOther]
+		}
+		return fmt.Sprintf(s, name, pipes)
+	}
+	return trn(eulogies[i%len(eulogies)], pipes, name, pipes)
+}
+
+// pluralCategory is one of the CLDR plural categories. Each language has a
+// rule to select the category for a number, see
+// https://cldr.unicode.org/index/cldr-spec/plural-rules
+type pluralCategory int
+
+const (
+	pluralOther pluralCategory = iota
+	pluralZero
+	pluralOne
+	pluralTwo
+	pluralFew
+	pluralMany
+
+	// NOTE pluralCategoryCount has to come last.
+	pluralCategoryCount
+)
+
+// message has a text for each plural category that its language uses. Texts
+// that do not depend on a number only have the other category.
+type message [pluralCategoryCount]string
+
+// text is a message that does not depend on a number.
+func text(s string) message {
+	var m message
+	m[pluralOther] = s
+	return m
+}
+
+// plural is a message for languages that only distinguish one from many.
+func plural(one, other string) message {
+	var m message
+	m[pluralOne] = one
+	m[pluralOther] = other
+	return m
+}
+
+type language struct {
+	// id is stored in the settings.
+	id string
+	// name is the language's name in the language itself. If our font cannot
+	// draw it, we show englishName instead.
+	name        string
+	englishName string
+	plural      func(n int) pluralCategory
+	messages    map[messageID]message
+	// missingTexts counts the texts in messages that contain characters which
+	// our font cannot draw. We show those texts in English instead.
+	missingTexts int
+}
+
+func oneOrOther(n int) pluralCategory {
+	if n == 1 {
+		return pluralOne
+	}
+	return pluralOther
+}
+
+// zeroOrOneOrOther is the French rule which uses the singular for 0 as well.
+func zeroOrOneOrOther(n int) pluralCategory {
+	if n == 0 || n == 1 {
+		return pluralOne
+	}
+	return pluralOther
+}
+
+// english is the fallback language, it must have all messages.
+var english = &language{
+	id:          "en",
+	name:        "English",
+	englishName: "English",
+	plural:      oneOrOther,
+	messages: map[messageID]message{
+		msgLoading:            text("Loading..."),
+		msgHighscore:          text("Highscore %d"),
+		msgDeadGophers:        plural("%d dead gopher so far", "%d dead gophers so far"),
+		msgNowPlaying:         text("now playing: %s"),
+		msgRecentlyDeceased:   text("recently deceased: %s"),
+		msgClickToRestart:     text("Click to Restart"),
+		msgPressToRestart:     text("Press %s to Restart"),
+		msgSetUpControls:      text("Press %s to set up the Controls"),
+		msgEulogy1:            plural("Our beloved %s passed after %d pipe", "Our beloved %s passed after %d pipes"),
+		msgEulogy2:            plural("%s left us peacefully after %d pipe", "%s left us peacefully after %d pipes"),
+		msgEulogy3:            plural("Our dear friend %s passed after %d pipe", "Our dear friend %s passed after %d pipes"),
+		msgEulogy4:            plural("%s passed quietly after %d pipe", "%s passed quietly after %d pipes"),
+		msgEulogy5:            plural("In loving memory of %s who cleared %d pipe", "In loving memory of %s who cleared %d pipes"),
+		msgEulogy6:            plural("Dear %s died after %d cleared pipe", "Dear %s died after %d cleared pipes"),
+		msgInHonorOfOurHeroes: plural("In Honor of our Hero", "In Honor of our Heroes"),
+		msgYouWillBeMissed:    text("You will be missed"),
+		msgStatistics:         text("Pipe Smoking Statistics"),
+		msgClearedPipes:       plural("%s cleared %d pipe", "%s cleared %d pipes"),
+		msgSettings:           text("Settings"),
+		msgSettingsHelp:       text("Click an action to change its bindings, click an option to change it, Escape goes back"),
+		msgCaptureHelp:        text("Press a key or mouse button to toggle it, Backspace clears all, Escape cancels"),
+		msgPressAKey:          text("press a key or mouse button..."),
+		msgKeepOneBinding:     text("%s needs at least one key or mouse button"),
+		msgResetControls:      text("Reset controls"),
+		msgActionFlap:         text("Flap"),
+		msgActionSettings:     text("Settings"),
+		msgActionName:         text("Name Gopher"),
+		msgActionQuit:         text("Quit"),
+		msgActionWardrobe:     text("Wardrobe"),
+		msgActionFullscreen:   text("Fullscreen"),
+		msgActionDebug:        text("Debug Overlay"),
+		msgActionScreenshot:   text("Screenshot"),
+		msgActionPhoto:        text("Photo Mode"),
+		msgOptionNames:        text("Names"),
+		msgOptionLanguage:     text("Language"),
+		msgLanguageIncomplete: plural("%s (%d text in English)", "%s (%d texts in English)"),
+		msgNameYourGopher:     text("Name your Gopher"),
+		msgNameHelp:           text("Enter to confirm, Escape to cancel, leave empty for random names"),
+		msgNameTooLong:        text("That name is too long"),
+		msgNameTooWide:        text("That name is too wide"),
+		msgNameCannotBeDrawn:  text("We cannot draw some of these letters"),
+		msgNamesEnglish:       text("English"),
+		msgNamesGerman:        text("German"),
+		msgNamesSpanish:       text("Spanish"),
+		msgNamesJapanese:      text("Japanese"),
+		msgNamesGophers:       text("Famous Gophers"),
+		msgNamesLanguages:     text("Programming Languages"),
+		msgNamesFile:          text("Your Name File"),
+		msgNamesCustom:        text("Your Own"),
+		msgAccessoryUnlocked:  text("New accessory unlocked: %s"),
+		msgWardrobe:           text("Wardrobe"),
+		msgWardrobeHelp:       text("Click or use the arrow keys to choose what your gophers wear, Escape goes back"),
+		msgWearRandom:         text("Random"),
+		msgWearNothing:        text("Nothing"),
+		msgLocked:             text("Locked: %s (%s)"),
+		msgUnlockPipes:        plural("clear %d pipe with one gopher", "clear %d pipes with one gopher"),
+		msgUnlockTotalPipes:   plural("clear %d pipe in total", "clear %d pipes in total"),
+		msgUnlockKills:        plural("lose %d gopher", "lose %d gophers"),
+		msgOptionPack:         text("Asset Pack"),
+		msgNoPack:             text("None"),
+		msgAccessoryCollision: text("Accessories Collide"),
+		msgOn:                 text("On"),
+		msgOff:                text("Off"),
+		msgAfterRestart:       text("%s (after restart)"),
+		msgPackNotLoaded:      text("Asset pack not loaded: %s"),
+		msgAccessoriesFailed:  text("Own accessories not loaded: %s"),
+		msgWeatherClear:       text("Clear"),
+		msgWeatherRain:        text("Rain"),
+		msgWeatherSnow:        text("Snow"),
+		msgWeatherStorm:       text("Storm"),
+		msgWeatherBlizzard:    text("Blizzard"),
+		msgWeatherRuns:        plural("%s: %d run, best %d", "%s: %d runs, best %d"),
+		msgNamesLegend:        text("Names:"),
+		msgLoadingFailed:      text("The game could not be loaded"),
+		msgPressEscapeToQuit:  text("Press Escape to quit"),
+		msgScreenshotSaved:    text("Screenshot saved: %s"),
+		msgScreenshotFailed:   text("Cannot save the screenshot: %v"),
+		msgPhotoModeHelp:      text("Photo mode - Left/Right or mouse wheel: look around, %s: screenshot, %s: back"),
+		msgOpponentScore:      text("Opponent %d"),
+	},
+}
+
+var german = &language{
+	id:          "de",
+	name:        "Deutsch",
+	englishName: "German",
+	plural:      oneOrOther,
+	messages: map[messageID]message{
+		msgLoading:            text("Lade..."),
+		msgHighscore:          text("Rekord %d"),
+		msgDeadGophers:        plural("bisher %d toter Gopher", "bisher %d tote Gopher"),
+		msgNowPlaying:         text("es spielt: %s"),
+		msgRecentlyDeceased:   text("kürzlich verstorben: %s"),
+		msgClickToRestart:     text("Klicken für Neustart"),
+		msgPressToRestart:     text("%s drücken für Neustart"),
+		msgSetUpControls:      text("%s drücken, um die Steuerung einzurichten"),
+		msgEulogy1:            plural("Unser geliebter %s ging nach %d Röhre von uns", "Unser geliebter %s ging nach %d Röhren von uns"),
+		msgEulogy2:            plural("%s verließ uns friedlich nach %d Röhre", "%s verließ uns friedlich nach %d Röhren"),
+		msgEulogy3:            plural("Unser lieber Freund %s starb nach %d Röhre", "Unser lieber Freund %s starb nach %d Röhren"),
+		msgEulogy4:            plural("%s entschlief still nach %d Röhre", "%s entschlief still nach %d Röhren"),
+		msgEulogy5:            plural("In liebevoller Erinnerung an %s, der %d Röhre schaffte", "In liebevoller Erinnerung an %s, der %d Röhren schaffte"),
+		msgEulogy6:            plural("Der liebe %s starb nach %d geschaffter Röhre", "Der liebe %s starb nach %d geschafften Röhren"),
+		msgInHonorOfOurHeroes: plural("Zu Ehren unseres Helden", "Zu Ehren unserer Helden"),
+		msgYouWillBeMissed:    text("Wir werden euch vermissen"),
+		msgStatistics:         text("Röhrenstatistik"),
+		msgClearedPipes:       plural("%s schaffte %d Röhre", "%s schaffte %d Röhren"),
+		msgSettings:           text("Einstellungen"),
+		msgSettingsHelp:       text("Aktion anklicken, um die Tasten zu ändern, Option anklicken, um sie zu ändern, Escape geht zurück"),
+		msgCaptureHelp:        text("Taste oder Maustaste drücken zum Umschalten, Rücktaste löscht alle, Escape bricht ab"),
+		msgPressAKey:          text("Taste oder Maustaste drücken..."),
+		msgKeepOneBinding:     text("%s braucht mindestens eine Taste oder Maustaste"),
+		msgResetControls:      text("Steuerung zurücksetzen"),
+		msgActionFlap:         text("Flattern"),
+		msgActionSettings:     text("Einstellungen"),
+		msgActionName:         text("Gopher benennen"),
+		msgActionQuit:         text("Beenden"),
+		msgActionWardrobe:     text("Kleiderschrank"),
+		msgActionFullscreen:   text("Vollbild"),
+		msgActionDebug:        text("Debug-Anzeige"),
+		msgActionScreenshot:   text("Bildschirmfoto"),
+		msgActionPhoto:        text("Fotomodus"),
+		msgOptionNames:        text("Namen"),
+		msgOptionLanguage:     text("Sprache"),
+		msgLanguageIncomplete: plural("%s (%d Text auf Englisch)", "%s (%d Texte auf Englisch)"),
+		msgNameYourGopher:     text("Benenne deinen Gopher"),
+		msgNameHelp:           text("Enter bestätigt, Escape bricht ab, leer lassen für zufällige Namen"),
+		msgNameTooLong:        text("Dieser Name ist zu lang"),
+		msgNameTooWide:        text("Dieser Name ist zu breit"),
+		msgNameCannotBeDrawn:  text("Einige dieser Zeichen können wir nicht darstellen"),
+		msgNamesEnglish:       text("Englisch"),
+		msgNamesGerman:        text("Deutsch"),
+		msgNamesSpanish:       text("Spanisch"),
+		msgNamesJapanese:      text("Japanisch"),
+		msgNamesGophers:       text("Berühmte Gopher"),
+		msgNamesLanguages:     text("Programmiersprachen"),
+		msgNamesFile:          text("Deine Namensdatei"),
+		msgNamesCustom:        text("Eigene"),
+		msgAccessoryUnlocked:  text("Neues Accessoire freigeschaltet: %s"),
+		msgWardrobe:           text("Kleiderschrank"),
+		msgWardrobeHelp:       text("Klicken oder Pfeiltasten, um zu wählen, was deine Gopher tragen, Escape geht zurück"),
+		msgWearRandom:         text("Zufällig"),
+		msgWearNothing:        text("Nichts"),
+		msgLocked:             text("Gesperrt: %s (%s)"),
+		msgUnlockPipes:        plural("%d Röhre mit einem Gopher schaffen", "%d Röhren mit einem Gopher schaffen"),
+		msgUnlockTotalPipes:   plural("insgesamt %d Röhre schaffen", "insgesamt %d Röhren schaffen"),
+		msgUnlockKills:        plural("%d Gopher verlieren", "%d Gopher verlieren"),
+		msgOptionPack:         text("Asset-Paket"),
+		msgNoPack:             text("Keins"),
+		msgAccessoryCollision: text("Accessoires kollidieren"),
+		msgOn:                 text("An"),
+		msgOff:                text("Aus"),
+		msgAfterRestart:       text("%s (nach Neustart)"),
+		msgPackNotLoaded:      text("Asset-Paket nicht geladen: %s"),
+		msgAccessoriesFailed:  text("Eigene Accessoires nicht geladen: %s"),
+		msgWeatherClear:       text("Klar"),
+		msgWeatherRain:        text("Regen"),
+		msgWeatherSnow:        text("Schnee"),
+		msgWeatherStorm:       text("Sturm"),
+		msgWeatherBlizzard:    text("Schneesturm"),
+		msgWeatherRuns:        plural("%s: %d Lauf, bester %d", "%s: %d Läufe, bester %d"),
+		msgNamesLegend:        text("Namen:"),
+		msgLoadingFailed:      text("Das Spiel konnte nicht geladen werden"),
+		msgPressEscapeToQuit:  text("Escape drücken zum Beenden"),
+		msgScreenshotSaved:    text("Bildschirmfoto gespeichert: %s"),
+		msgScreenshotFailed:   text("Bildschirmfoto kann nicht gespeichert werden: %v"),
+		msgPhotoModeHelp:      text("Fotomodus - Links/Rechts oder Mausrad: umsehen, %s: Bildschirmfoto, %s: zurück"),
+		msgOpponentScore:      text("Gegner %d"),
+	},
+}
+
+var spanish = &language{
+	id:          "es",
+	name:        "Español",
+	englishName: "Spanish",
+	plural:      oneOrOther,
+	messages: map[messageID]message{
+		msgLoading:            text("Cargando..."),
+		msgHighscore:          text("Récord %d"),
+		msgDeadGophers:        plural("%d gopher muerto hasta ahora", "%d gophers muertos hasta ahora"),
+		msgNowPlaying:         text("jugando: %s"),
+		msgRecentlyDeceased:   text("fallecido recientemente: %s"),
+		msgClickToRestart:     text("Haz clic para reiniciar"),
+		msgPressToRestart:     text("Pulsa %s para reiniciar"),
+		msgSetUpControls:      text("Pulsa %s para configurar los controles"),
+		msgEulogy1:            plural("Nuestro querido %s nos dejó tras %d tubería", "Nuestro querido %s nos dejó tras %d tuberías"),
+		msgEulogy2:            plural("%s nos dejó en paz tras %d tubería", "%s nos dejó en paz tras %d tuberías"),
+		msgEulogy3:            plural("Nuestro buen amigo %s falleció tras %d tubería", "Nuestro buen amigo %s falleció tras %d tuberías"),
+		msgEulogy4:            plural("%s se fue en silencio tras %d tubería", "%s se fue en silencio tras %d tuberías"),
+		msgEulogy5:            plural("En memoria de %s, que superó %d tubería", "En memoria de %s, que superó %d tuberías"),
+		msgEulogy6:            plural("El querido %s murió tras superar %d tubería", "El querido %s murió tras superar %d tuberías"),
+		msgInHonorOfOurHeroes: plural("En honor a nuestro héroe", "En honor a nuestros héroes"),
+		msgYouWillBeMissed:    text("Os echaremos de menos"),
+		msgStatistics:         text("Estadísticas de tuberías"),
+		msgClearedPipes:       plural("%s superó %d tubería", "%s superó %d tuberías"),
+		msgSettings:           text("Ajustes"),
+		msgSettingsHelp:       text("Haz clic en una acción o una opción para cambiarla, Escape vuelve"),
+		msgCaptureHelp:        text("Pulsa una tecla o un botón del ratón, Retroceso borra todo, Escape cancela"),
+		msgPressAKey:          text("pulsa una tecla o un botón del ratón..."),
+		msgKeepOneBinding:     text("%s necesita al menos una tecla o un botón del ratón"),
+		msgResetControls:      text("Restablecer controles"),
+		msgActionFlap:         text("Aletear"),
+		msgActionSettings:     text("Ajustes"),
+		msgActionName:         text("Nombrar gopher"),
+		msgActionQuit:         text("Salir"),
+		msgActionWardrobe:     text("Armario"),
+		msgActionFullscreen:   text("Pantalla completa"),
+		msgActionDebug:        text("Capa de depuración"),
+		msgActionScreenshot:   text("Captura de pantalla"),
+		msgActionPhoto:        text("Modo foto"),
+		msgOptionNames:        text("Nombres"),
+		msgOptionLanguage:     text("Idioma"),
+		msgLanguageIncomplete: plural("%s (%d texto en inglés)", "%s (%d textos en inglés)"),
+		msgNameYourGopher:     text("Nombra a tu gopher"),
+		msgNameHelp:           text("Enter confirma, Escape cancela, vacío para nombres aleatorios"),
+		msgNameTooLong:        text("Ese nombre es demasiado largo"),
+		msgNameTooWide:        text("Ese nombre es demasiado ancho"),
+		msgNameCannotBeDrawn:  text("No podemos dibujar algunas de estas letras"),
+		msgNamesEnglish:       text("Inglés"),
+		msgNamesGerman:        text("Alemán"),
+		msgNamesSpanish:       text("Español"),
+		msgNamesJapanese:      text("Japonés"),
+		msgNamesGophers:       text("Gophers famosos"),
+		msgNamesLanguages:     text("Lenguajes de programación"),
+		msgNamesFile:          text("Tu archivo de nombres"),
+		msgNamesCustom:        text("Propios"),
+		msgAccessoryUnlocked:  text("Nuevo accesorio desbloqueado: %s"),
+		msgWardrobe:           text("Armario"),
+		msgWardrobeHelp:       text("Haz clic o usa las flechas para elegir qué llevan tus gophers, Escape vuelve"),
+		msgWearRandom:         text("Aleatorio"),
+		msgWearNothing:        text("Nada"),
+		msgLocked:             text("Bloqueado: %s (%s)"),
+		msgUnlockPipes:        plural("supera %d tubería con un gopher", "supera %d tuberías con un gopher"),
+		msgUnlockTotalPipes:   plural("supera %d tubería en total", "supera %d tuberías en total"),
+		msgUnlockKills:        plural("pierde %d gopher", "pierde %d gophers"),
+		msgOptionPack:         text("Paquete de recursos"),
+		msgNoPack:             text("Ninguno"),
+		msgAccessoryCollision: text("Accesorios chocan"),
+		msgOn:                 text("Sí"),
+		msgOff:                text("No"),
+		msgAfterRestart:       text("%s (tras reiniciar)"),
+		msgPackNotLoaded:      text("Paquete de recursos no cargado: %s"),
+		msgAccessoriesFailed:  text("Accesorios propios no cargados: %s"),
+		msgWeatherClear:       text("Despejado"),
+		msgWeatherRain:        text("Lluvia"),
+		msgWeatherSnow:        text("Nieve"),
+		msgWeatherStorm:       text("Tormenta"),
+		msgWeatherBlizzard:    text("Ventisca"),
+		msgWeatherRuns:        plural("%s: %d partida, mejor %d", "%s: %d partidas, mejor %d"),
+		msgNamesLegend:        text("Nombres:"),
+		msgLoadingFailed:      text("No se pudo cargar el juego"),
+		msgPressEscapeToQuit:  text("Pulsa Escape para salir"),
+		msgScreenshotSaved:    text("Captura guardada: %s"),
+		msgScreenshotFailed:   text("No se puede guardar la captura: %v"),
+		msgPhotoModeHelp:      text("Modo foto - Izquierda/Derecha o rueda del ratón: mirar alrededor, %s: captura, %s: volver"),
+		msgOpponentScore:      text("Rival %d"),
+	},
+}
+
+var french = &language{
+	id:          "fr",
+	name:        "Français",
+	englishName: "French",
+	plural:      zeroOrOneOrOther,
+	messages: map[messageID]message{
+		msgLoading:            text("Chargement..."),
+		msgHighscore:          text("Record %d"),
+		msgDeadGophers:        plural("%d gopher mort jusqu'ici", "%d gophers morts jusqu'ici"),
+		msgNowPlaying:         text("en jeu : %s"),
+		msgRecentlyDeceased:   text("récemment décédé : %s"),
+		msgClickToRestart:     text("Cliquez pour recommencer"),
+		msgPressToRestart:     text("Appuyez sur %s pour recommencer"),
+		msgSetUpControls:      text("Appuyez sur %s pour configurer les commandes"),
+		msgEulogy1:            plural("Notre cher %s nous a quittés après %d tuyau", "Notre cher %s nous a quittés après %d tuyaux"),
+		msgEulogy2:            plural("%s est parti paisiblement après %d tuyau", "%s est parti paisiblement après %d tuyaux"),
+		msgEulogy3:            plural("Notre ami %s est décédé après %d tuyau", "Notre ami %s est décédé après %d tuyaux"),
+		msgEulogy4:            plural("%s s'est éteint après %d tuyau", "%s s'est éteint après %d tuyaux"),
+		msgEulogy5:            plural("À la mémoire de %s qui a franchi %d tuyau", "À la mémoire de %s qui a franchi %d tuyaux"),
+		msgEulogy6:            plural("Le cher %s est mort après %d tuyau franchi", "Le cher %s est mort après %d tuyaux franchis"),
+		msgInHonorOfOurHeroes: plural("En l'honneur de notre héros", "En l'honneur de nos héros"),
+		msgYouWillBeMissed:    text("Vous nous manquerez"),
+		msgStatistics:         text("Statistiques des tuyaux"),
+		msgClearedPipes:       plural("%s a franchi %d tuyau", "%s a franchi %d tuyaux"),
+		msgSettings:           text("Réglages"),
+		msgSettingsHelp:       text("Cliquez sur une action ou une option pour la changer, Échap pour revenir"),
+		msgCaptureHelp:        text("Appuyez sur une touche ou un bouton, Retour arrière efface tout, Échap annule"),
+		msgPressAKey:          text("appuyez sur une touche ou un bouton..."),
+		msgKeepOneBinding:     text("%s a besoin d'au moins une touche ou un bouton"),
+		msgResetControls:      text("Réinitialiser les commandes"),
+		msgActionFlap:         text("Battre des ailes"),
+		msgActionSettings:     text("Réglages"),
+		msgActionName:         text("Nommer le gopher"),
+		msgActionQuit:         text("Quitter"),
+		msgActionWardrobe:     text("Garde-robe"),
+		msgActionFullscreen:   text("Plein écran"),
+		msgActionDebug:        text("Affichage de débogage"),
+		msgActionScreenshot:   text("Capture d'écran"),
+		msgActionPhoto:        text("Mode photo"),
+		msgOptionNames:        text("Noms"),
+		msgOptionLanguage:     text("Langue"),
+		msgLanguageIncomplete: plural("%s (%d texte en anglais)", "%s (%d textes en anglais)"),
+		msgNameYourGopher:     text("Nommez votre gopher"),
+		msgNameHelp:           text("Entrée pour valider, Échap pour annuler, vide pour des noms aléatoires"),
+		msgNameTooLong:        text("Ce nom est trop long"),
+		msgNameTooWide:        text("Ce nom est trop large"),
+		msgNameCannotBeDrawn:  text("Nous ne pouvons pas dessiner certaines lettres"),
+		msgNamesEnglish:       text("Anglais"),
+		msgNamesGerman:        text("Allemand"),
+		msgNamesSpanish:       text("Espagnol"),
+		msgNamesJapanese:      text("Japonais"),
+		msgNamesGophers:       text("Gophers célèbres"),
+		msgNamesLanguages:     text("Langages de programmation"),
+		msgNamesFile:          text("Votre fichier de noms"),
+		msgNamesCustom:        text("Les vôtres"),
+		msgAccessoryUnlocked:  text("Nouvel accessoire débloqué : %s"),
+		msgWardrobe:           text("Garde-robe"),
+		msgWardrobeHelp:       text("Cliquez ou utilisez les flèches pour habiller vos gophers, Échap pour revenir"),
+		msgWearRandom:         text("Au hasard"),
+		msgWearNothing:        text("Rien"),
+		msgLocked:             text("Verrouillé : %s (%s)"),
+		msgUnlockPipes:        plural("passer %d tuyau avec un gopher", "passer %d tuyaux avec un gopher"),
+		msgUnlockTotalPipes:   plural("passer %d tuyau au total", "passer %d tuyaux au total"),
+		msgUnlockKills:        plural("perdre %d gopher", "perdre %d gophers"),
+		msgOptionPack:         text("Pack de ressources"),
+		msgNoPack:             text("Aucun"),
+		msgAccessoryCollision: text("Accessoires en collision"),
+		msgOn:                 text("Oui"),
+		msgOff:                text("Non"),
+		msgAfterRestart:       text("%s (après redémarrage)"),
+		msgPackNotLoaded:      text("Pack de ressources non chargé : %s"),
+		msgAccessoriesFailed:  text("Accessoires personnels non chargés : %s"),
+		msgWeatherClear:       text("Dégagé"),
+		msgWeatherRain:        text("Pluie"),
+		msgWeatherSnow:        text("Neige"),
+		msgWeatherStorm:       text("Orage"),
+		msgWeatherBlizzard:    text("Blizzard"),
+		msgWeatherRuns:        plural("%s : %d partie, meilleur %d", "%s : %d parties, meilleur %d"),
+		msgNamesLegend:        text("Noms :"),
+		msgLoadingFailed:      text("Le jeu n'a pas pu être chargé"),
+		msgPressEscapeToQuit:  text("Appuyez sur Échap pour quitter"),
+		msgScreenshotSaved:    text("Capture enregistrée : %s"),
+		msgScreenshotFailed:   text("Impossible d'enregistrer la capture : %v"),
+		msgPhotoModeHelp:      text("Mode photo - Gauche/Droite ou molette : regarder autour, %s : capture, %s : retour"),
+		msgOpponentScore:      text("Adversaire %d"),
+	},
+}
+
+var languages = []*language{english, german, spanish, french}
+
+// pluralSamples are numbers which together cover all plural categories of our
+// languages. Use them to find the widest version of a text.
+var pluralSamples = []int{0, 1, 2, 3, 5, 11, 21, 100}
+
+// currentLanguage is used by tr and trn. Change it with setLanguage.
+var currentLanguage = english
+
+func init() {
+	for id := range messageCount {
+		if english.messages[id][pluralOther] == "" {
+			panic(fmt.Sprintf("message %d is missing in English", id))
+		}
+	}
+
+	// Texts that our font cannot draw are removed from the catalogs so we
+	// fall back to English for them.
+	for _, lang := range languages {
+		for id, m := range lang.messages {
+			for category, s := range m {
+				if s != "" && !canDraw(s) {
+					m[category] = ""
+					lang.missingTexts++
+				}
+			}
+			lang.messages[id] = m
+		}
+	}
+}
+
+// findLanguage returns the language with the given id or English if there is
+// no such language.
+func findLanguage(id string) *language {
+	for _, lang := range languages {
+		if lang.id == id {
+			return lang
+		}
+	}
+	return english
+}
+
+// title is the language's name as shown in the settings.
+func (l *language) title() string {
+	if canDraw(l.name) {
+		return l.name
+	}
+	return l.englishName
+}
+
+func setLanguage(id string) {
+	currentLanguage = findLanguage(id)
+}
+
+// tr translates the message into the current language and formats it with the
+// given arguments.
+func tr(id messageID, args ...any) string {
+	return format(lookup(id, pluralOther, pluralOther), args)
+}
+
+// trn translates a message that depends on the number n, choosing the plural
+// form according to the current language's plural rules.
+func trn(id messageID, n int, args ...any) string {
+	return format(lookup(id, currentLanguage.plural(n), english.plural(n)), args)
+}
+
+// lookup falls back to English if the current language does not have the text.
+// English can have a different plural category for the same number, which is
+// why the caller passes both.
+func lookup(id messageID, category, englishCategory pluralCategory) string {
+	if s := currentLanguage.messages[id][category]; s != "" {
+		return s
+	}
+	if s := english.messages[id][englishCategory]; s != "" {
+		return s
+	}
+	return english.messages[id][pluralOther]
+}
+
+func format(s string, args []any) string {
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
+
+// fontGlyphs are the characters in the prototype/draw library's built-in font
+// on top of ASCII. Any other character is drawn as an empty space. The font
+// has the symbols at positions 1 to 31 and the letters from position 128 on.
+// The lookalikes are drawn with the ASCII characters in fontLookalikesASCII.
+const (
+	fontSymbols         = "☺☻♥♦♣♠•◘○◙♂♀♪♫☼►◄↕‼¶§▬↨↑↓→←∟↔▲▼"
+	fontLetters         = "âáàêéèîíìôóòûúùÂÁÀÊÉÈÎÍÌÔÓÒÛÚÙäëïöüÄËÏÖÜåůÅŮçÇß²³´°æÆ"
+	fontLookalikes      = "ЅІЈАВЕЗКМНОРСТУХЬавгезкмнорстухъьѕіјѡѴѵ"
+	fontLookalikesASCII = "SIJABE3KMHOPCTyXbaBre3KMHopcTyxbbsijwVv"
+	fontGlyphs          = fontSymbols + fontLetters + fontLookalikes
+)
+
+// canDraw reports whether all characters in s are in our font.
+func canDraw(s string) bool {
+	for _, r := range s {
+		if !(32 <= r && r <= 127) && !strings.ContainsRune(fontGlyphs, r) {
+			return false
+		}
+	}
+	return true
+}
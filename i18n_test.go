@@ -0,0 +1,39 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// formatVerb matches the verbs of fmt.Sprintf, %% is not one.
+var formatVerb = regexp.MustCompile(`%[-+# 0]*[0-9]*(\.[0-9]*)?[a-zA-Z%]`)
+
+func formatVerbs(s string) []string {
+	var verbs []string
+	for _, verb := range formatVerb.FindAllString(s, -1) {
+		if verb != "%%" {
+			verbs = append(verbs, verb)
+		}
+	}
+	return verbs
+}
+
+func TestCatalogsHaveTheEnglishFormatVerbs(t *testing.T) {
+	for _, lang := range languages {
+		for id := range messageCount {
+			want := formatVerbs(english.messages[id][pluralOther])
+			for category, s := range lang.messages[id] {
+				// Texts that our font cannot draw are empty, English is
+				// shown instead.
+				if s == "" {
+					continue
+				}
+				if have := formatVerbs(s); !slices.Equal(have, want) {
+					t.Errorf("%s message %d in plural category %d has the verbs %v instead of %v: %q",
+						lang.id, id, category, have, want, s)
+				}
+			}
+		}
+	}
+}
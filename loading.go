@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// placeholderImage is drawn instead of accessory images that cannot be loaded.
+// It is not a real file, see placeholderPNG.
+const placeholderImage = "placeholder.png"
+
+// loader loads all images and checks all sounds before the game starts. In the
+// browser, images load in the background so this takes a few frames. On the
+// desktop, we load a few files per frame to be able to show the progress.
+type loader struct {
+	files  []string
+	loaded []bool
+	count  int
+	// optional are the accessory images. If one of them cannot be loaded, we
+	// draw the placeholderImage instead of failing.
+	optional  map[string]bool
+	accessory *accessoryManifest
+	err       error
+}
+
+// newLoader loads all images and sounds in the rsc folder, the accessory images
+// and the given extra images, e.g. tinted images.
+func newLoader(accessories *accessoryManifest, images []string) *loader {
+	l := &loader{
+		optional:  make(map[string]bool),
+		accessory: accessories,
+	}
+	entries, _ := fs.ReadDir(assets, "rsc")
+	for _, e := range entries {
+		file := "rsc/" + e.Name()
+		if ext := path.Ext(file); ext == ".png" || ext == ".wav" {
+			l.files = append(l.files, file)
+		}
+	}
+	for _, image := range images {
+		if !slices.Contains(l.files, image) {
+			l.files = append(l.files, image)
+		}
+	}
+	for _, a := range accessories.Accessories {
+		if !slices.Contains(l.files, a.Image) {
+			l.files = append(l.files, a.Image)
+		}
+		l.optional[a.Image] = true
+	}
+	l.loaded = make([]bool, len(l.files))
+	return l
+}
+
+func (l *loader) done() bool {
+	return l.count == len(l.files)
+}
+
+func (l *loader) progress() float64 {
+	if len(l.files) == 0 {
+		return 1
+	}
+	return float64(l.count) / float64(len(l.files))
+}
+
+// update continues loading. If a file cannot be loaded, err is set and the
+// game cannot start.
+func (l *loader) update(window draw.Window) {
+	// Loading an image on the desktop blocks, so we stop after some time to
+	// draw the progress.
+	const frameBudget = 10 * time.Millisecond
+	start := time.Now()
+
+	for i, file := range l.files {
+		if l.err != nil || time.Since(start) > frameBudget {
+			return
+		}
+		if l.loaded[i] {
+			continue
+		}
+
+		var err error
+		if path.Ext(file) == ".wav" {
+			err = checkSound(file)
+		} else {
+			_, _, err = window.ImageSize(file)
+			if err == draw.ErrImageLoading {
+				continue
+			}
+		}
+
+		if err != nil && l.optional[file] {
+			l.accessory.replaceImage(file, placeholderImage)
+			err = nil
+		}
+		if err != nil {
+			l.err = fmt.Errorf("%s: %w", file, err)
+			return
+		}
+		l.loaded[i] = true
+		l.count++
+	}
+}
+
+// checkSound makes sure that the sound exists and is a WAV file. The draw
+// package loads sounds when they are first played and does not tell us about
+// errors.
+func checkSound(file string) error {
+	data, err := fs.ReadFile(assets, file)
+	if err != nil {
+		return err
+	}
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return errors.New("not a WAV file")
+	}
+	return nil
+}
+
+func (l *loader) draw(window renderer, backgroundColor draw.Color) {
+	windowW, windowH := window.Size()
+	window.FillRect(0, 0, windowW, windowH, backgroundColor)
+
+	const textScale = 3
+	text := tr(msgLoading)
+	textW, textH := window.GetScaledTextSize(text, textScale)
+	window.DrawScaledText(text, (windowW-textW)/2, windowH/2-textH-30, textScale, draw.Black)
+
+	const barW, barH = 600, 30
+	barX, barY := (windowW-barW)/2, windowH/2
+	window.FillRect(barX, barY, barW, barH, draw.RGBA(1, 1, 1, 0.6))
+	window.FillRect(barX, barY, round(barW*l.progress()), barH, draw.RGB(0.5, 0, 0))
+	window.DrawRect(barX, barY, barW, barH, draw.Black)
+}
+
+// drawLoadingError replaces the game if we cannot load all of our files.
+func drawLoadingError(window renderer, err error, backgroundColor draw.Color) {
+	windowW, windowH := window.Size()
+	window.FillRect(0, 0, windowW, windowH, backgroundColor)
+
+	const (
+		titleScale = 4
+		textScale  = 2
+		margin     = 60
+	)
+	title := tr(msgLoadingFailed)
+	titleW, titleH := window.GetScaledTextSize(title, titleScale)
+	y := windowH/3 - titleH
+	window.DrawScaledText(title, (windowW-titleW)/2, y, titleScale, draw.Black)
+	y += titleH + 30
+
+	for _, line := range wrapText(window, err.Error(), textScale, windowW-2*margin) {
+		lineW, lineH := window.GetScaledTextSize(line, textScale)
+		window.DrawScaledText(line, (windowW-lineW)/2, y, textScale, draw.RGB(0.8, 0, 0))
+		y += lineH
+	}
+
+	help := tr(msgPressEscapeToQuit)
+	helpW, helpH := window.GetScaledTextSize(help, textScale)
+	window.DrawScaledText(help, (windowW-helpW)/2, windowH-helpH-40, textScale, draw.Black)
+}
+
+// wrapText breaks the text into lines at spaces so that every line fits into
+// the given width, if possible.
+func wrapText(window renderer, text string, scale float32, width int) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(text) {
+		next := word
+		if line != "" {
+			next = line + " " + word
+		}
+		if w, _ := window.GetScaledTextSize(next, scale); w > width && line != "" {
+			lines = append(lines, line)
+			next = word
+		}
+		line = next
+	}
+	return append(lines, line)
+}
+
+// placeholder caches placeholderPNG, see openAsset.
+var placeholder []byte
+
+// openAsset opens the files that we draw and play, the assets as well as the
+// placeholderImage and tinted images that are made up on the fly.
+func openAsset(path string) (io.ReadCloser, error) {
+	if path == placeholderImage {
+		if placeholder == nil {
+			placeholder = placeholderPNG()
+		}
+		return io.NopCloser(bytes.NewReader(placeholder)), nil
+	}
+	if strings.HasPrefix(path, tintPrefix) {
+		data, err := openTintedImage(path)
+		return io.NopCloser(bytes.NewReader(data)), err
+	}
+	return assets.Open(path)
+}
+
+// placeholderPNG is the image file for placeholderImage. It has the size of
+// the gopher and a checkerboard in the middle that is easy to spot.
+func placeholderPNG() []byte {
+	w, h, err := imageFileSize(assets, "rsc/arms_center.png")
+	if err != nil {
+		w, h = 130, 158
+	}
+	const tiles, tileSize = 4, 8
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	left := (w - tiles*tileSize) / 2
+	top := (h - tiles*tileSize) / 2
+	for y := range tiles * tileSize {
+		for x := range tiles * tileSize {
+			c := color.NRGBA{255, 0, 255, 255}
+			if (x/tileSize+y/tileSize)%2 == 1 {
+				c = color.NRGBA{0, 0, 0, 255}
+			}
+			img.SetNRGBA(left+x, top+y, c)
+		}
+	}
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	return buf.Bytes()
+}
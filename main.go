@@ -3,15 +3,16 @@ package main
 import (
 	"bytes"
 	"embed"
+	"flag"
 	"fmt"
-	"io"
 	"math"
 	"math/rand"
+	"net/url"
+	"os"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
-	"unicode/utf8"
 
 	"github.com/gonutz/prototype/draw"
 )
@@ -19,80 +20,88 @@ import (
 //go:embed rsc
 var rsc embed.FS
 
+const (
+	tailDownImage   = "rsc/tail_down.png"
+	tailCenterImage = "rsc/tail_center.png"
+	tailUpImage     = "rsc/tail_up.png"
+	deadFrame       = "rsc/dead.png"
+	bumpFrame       = "rsc/bump.png"
+	pipeImage       = "rsc/pipe.png"
+	cloudImage      = "rsc/cloud.png"
+)
+
+// commands run instead of the game, e.g. "flappy export", see the readme. They get
+// the arguments after their name.
+var commands = map[string]func(args []string, m *accessoryManifest) error{
+	"export":   runExport,
+	"env":      runEnv,
+	"train":    runTrain,
+	"simulate": runSimulate,
+}
+
 func main() {
-	draw.OpenFile = func(path string) (io.ReadCloser, error) {
-		return rsc.Open(path)
+	packPath := flag.String("pack", "", "asset pack `directory or zip file`, overrides the pack from the settings")
+	tty := flag.Bool("tty", false, "play in the terminal instead of a window, e.g. over SSH")
+	opponentPath := flag.String("opponent", "", "network `file` from flappy train that flies along as an opponent")
+	gapPattern := flag.String("gaps", defaultGapPattern, "`pattern` of the gaps: "+strings.Join(gapPatternNames(), ", "))
+	flag.Parse()
+
+	settings := loadSettings()
+	setLanguage(settings.language)
+	reloadUserNames()
+
+	if *packPath == "" {
+		*packPath = settings.pack
+	}
+	packErr := useAssetPack(*packPath)
+
+	draw.OpenFile = openAsset
+
+	accessoryManifest, accessoryErr := loadAccessoryManifest()
+
+	if run, ok := commands[flag.Arg(0)]; ok {
+		if err := run(flag.Args()[1:], accessoryManifest); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
 
-	animationFrames := []string{
-		"rsc/arms_center.png",
-		"rsc/arms_up.png",
-		"rsc/arms_center.png",
-		"rsc/arms_down.png",
+	if _, ok := gapPatterns[*gapPattern]; !ok {
+		fmt.Fprintf(os.Stderr, "unknown gap pattern %q, use one of %s\n", *gapPattern, strings.Join(gapPatternNames(), ", "))
+		os.Exit(1)
 	}
-	// At most one item from each accessory group is picked.
-	accessoryGroups := [][]string{
-		{"hat"},
-		{"tie", "bowtie", "shirt"},
-		{"round_glasses", "square_glasses", "sunglasses"},
-		{"earring"},
+
+	// The opponent is a network that plays the same gaps as the player.
+	var opponentNetwork *network
+	if *opponentPath != "" {
+		var err error
+		opponentNetwork, err = loadNetwork(*opponentPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
 	}
-	backgroundImages := []string{
-		"rsc/city0.png",
-		"rsc/city1.png",
+
+	if *tty {
+		if err := runTerminal(settings, *gapPattern, accessoryManifest); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
 	}
-	backgroundColor := rgb(151, 255, 255)
+
 	const (
-		windowW, windowH           = 1500, 800
-		tailDownImage              = "rsc/tail_down.png"
-		tailCenterImage            = "rsc/tail_center.png"
-		tailUpImage                = "rsc/tail_up.png"
-		deadFrame                  = "rsc/dead.png"
-		bumpFrame                  = "rsc/bump.png"
-		pipeImage                  = "rsc/pipe.png"
-		cloudImage                 = "rsc/cloud.png"
-		gopherSpeed                = 5
-		gravity                    = 0.5
-		gapHeight                  = 300
-		firstGapX                  = 1300
-		gapDistX                   = 600
-		finalGopherX               = 100
-		gopherCollisionRadius      = 50
-		clickYSpeed                = -14.0
-		minVisiblePipeHeight       = 80
-		pipeShakeFrameCount        = 40
-		musicIntroFile             = "rsc/music_intro.wav"
-		musicIntroLengthInSeconds  = 6
-		musicLoopFile              = "rsc/music_loop.wav"
-		musicLoopLengthInSeconds   = 14
-		deceasedTextFadeFrameCount = 60
-		cursorHideTimeout          = 120
+		musicIntroFile            = "rsc/music_intro.wav"
+		musicIntroLengthInSeconds = 6
+		musicLoopFile             = "rsc/music_loop.wav"
+		musicLoopLengthInSeconds  = 14
+		cursorHideTimeout         = 120
 	)
 
 	var (
-		animationIndex      int
-		nextFlapIn          int
-		gopherXOffset       int
-		x                   float64
-		y                   float64
-		xSpeed              float64
-		ySpeed              float64
-		rotation            float64
-		targetRotation      float64
-		isAlive             bool
-		gaps                [10]gap
-		nextGapX            int
-		score               int
-		scoreAnimationTime  float64
-		restartableTime     int
-		backgroundTiles     []backgroundTile
-		highscore           int
-		needToPlayFlapSound bool
-		flapSoundCoolDown   int
-		playDeathSoundIn    int
-		bumpOnHead          bool
-		clouds              [6]cloud
-		accessories         []string
+		restartableTime int
+		newAccessories  []accessory
 		// killCount is not always the same as len(killHistory). When we kill
 		// the latest gopher, we add it to the killHistory right away, but we
 		// wait for the restart screen until we update the kill count in the
@@ -101,796 +110,437 @@ func main() {
 		killHistory        []kill
 		wasRestartable     bool
 		name               string
-		nameAlpha          float32
-		nameAnimationTime  int
-		deceasedTextTime   int
+		nameSource         string
 		hideCursorInFrames int
-		killScrollY        int
 	)
 
-	randomGapY := func() int {
-		top := gapHeight/2 + minVisiblePipeHeight
-		bottom := windowH - gapHeight/2 - minVisiblePipeHeight
-		return top + rand.Intn(bottom-top)
+	// If the game's images cannot be loaded, the loader shows the error and
+	// the game never starts.
+	g, err := newGame(accessoryManifest)
+	if err != nil {
+		g = &game{manifest: accessoryManifest}
 	}
-
-	randomCityImage := func() string {
-		i := rand.Intn(len(backgroundImages))
-		return backgroundImages[i]
+	g.gapPattern = *gapPattern
+	var opponent *game
+	if opponentNetwork != nil && err == nil {
+		opponent, _ = newGame(accessoryManifest)
+		opponent.gapPattern = *gapPattern
 	}
 
-	randomCloudScale := func() float64 {
-		return 0.5 + rand.Float64()*0.5
+	// The loader loads everything in the rsc folder but the tinted images
+	// are not in there.
+	var extraImages []string
+	for _, b := range biomes {
+		extraImages = append(extraImages, tintedImage(pipeImage, b.pipeTint))
 	}
-
-	randomCloudY := func() int {
-		minY := -100
-		maxY := 360
-		return minY + rand.Intn(maxY-minY)
-	}
-
-	// We have a fixed number of names. We use up all names first, then
-	// re-shuffle the list and re-use names. This might happen many times.
-	// For each new deck of names we shuffle deterministically using seed 1 for
-	// the first shuffle, seed 2 for the second, seed 3 for the third and so on.
-	// This way we always know which name comes at which position and we can
-	// re-create this scheme between two runs of the program by simply looking
-	// at how many names are in the kill history.
-	// This scheme relies on two things:
-	// 1. The random number generation being the same, which is promised by Go.
-	// 2. The names being the same which might not be true. Whenever we add a
-	//    name we change the random name generation. This is still good enough for
-	//    a Flappy Bird clone :-)
-	var restNames []string
-	randomName := func() string {
-		if len(restNames) == 0 {
-			restNames = slices.Clone(nameList)
-			seed := len(killHistory) / len(nameList)
-			rng := rand.New(rand.NewSource(int64(seed)))
-			shuffleStrings(rng, restNames)
-
-			used := len(killHistory) % len(nameList)
-			restNames = restNames[used:]
-		}
-
-		name := restNames[0]
-		restNames = restNames[1:]
-		return name
+	for _, layer := range g.parallax {
+		extraImages = append(extraImages, layer.allImages()...)
 	}
 
 	restart := func() {
-		animationIndex = 0
-		nextFlapIn = 0
-		gopherXOffset = -finalGopherX - 150
-		x = 0.0
-		y = 400.0
-		xSpeed = 0.0
-		ySpeed = clickYSpeed
-		rotation = 0.0
-		targetRotation = 0.0
-		isAlive = true
-		nextGapX = firstGapX
-		for i := range gaps {
-			gaps[i] = gap{}
-			gaps[i].centerX = nextGapX
-			gaps[i].centerY = randomGapY()
-			nextGapX += gapDistX
-		}
-		score = 0
-		scoreAnimationTime = 0.0
 		restartableTime = 0
-		backgroundTiles = backgroundTiles[:0]
 		killHistory = loadKillHistory()
 		killCount = len(killHistory)
-		highscore = 0
-		for _, k := range killHistory {
-			highscore = max(highscore, k.Score)
-		}
-		needToPlayFlapSound = true
-		playDeathSoundIn = 0
-		bumpOnHead = false
-		for i := range clouds {
-			clouds[i].scale = randomCloudScale()
-			clouds[i].x = float64(-350 + rand.Intn(windowW+350))
-			clouds[i].y = randomCloudY()
-		}
-
-		lastAccessories := slices.Clone(accessories)
-		for slices.Equal(lastAccessories, accessories) {
-			accessories = accessories[:0]
-			accessoryChance := 0.33
-			for _, group := range accessoryGroups {
-				if rand.Float64() < accessoryChance {
-					i := rand.Intn(len(group))
-					accessories = append(accessories, group[i])
-				}
+
+		// Make every gopher look different from the last one. If only few
+		// accessories are unlocked, this might not be possible.
+		lastAccessories := slices.Clone(g.accessories)
+		stats := statsOf(killHistory)
+		var accessories []string
+		for range 10 {
+			accessories = accessoryManifest.roll(stats, settings.wardrobe)
+			if !slices.Equal(lastAccessories, accessories) {
+				break
 			}
 		}
+		newAccessories = nil
 		wasRestartable = false
-		name = randomName()
-		nameAlpha = 1.0
-		nameAnimationTime = 0
-		deceasedTextTime = deceasedTextFadeFrameCount
+		name, nameSource = settings.customName, customNameSource
+		if name == "" {
+			name, nameSource = randomName(settings, killHistory)
+		}
 		hideCursorInFrames = cursorHideTimeout
-		killScrollY = 0
-	}
-	restart()
-
-	preloadImage := func(window draw.Window) bool {
-		preloaded := true
-
-		preload := func(img string) {
-			_, _, err := window.ImageSize(img)
-
-			if err == draw.ErrImageLoading {
-				preloaded = false
-			} else if err != nil {
-				panic(err)
-			}
+		seed := rand.Int63()
+		g.reset(seed, accessories)
+		if opponent != nil {
+			opponent.reset(seed, accessoryManifest.roll(stats, nil))
 		}
-
-		files, _ := rsc.ReadDir("rsc")
-		for _, file := range files {
-			path := "rsc/" + file.Name()
-			if strings.HasSuffix(path, ".png") {
-				preload(path)
-			}
+		g.highscore = 0
+		for _, k := range killHistory {
+			g.highscore = max(g.highscore, k.Score)
 		}
-
-		return preloaded
 	}
+	restart()
 
-	imagesAreLoaded := false
+	loader := newLoader(accessoryManifest, extraImages)
 	var nextMusicStart time.Time
 	var lastMouseX, lastMouseY int
-
-	draw.RunWindow("Flappy Go", windowW, windowH, func(window draw.Window) {
-		window.SetIcon("rsc/icon.png")
-
-		if !imagesAreLoaded {
-			imagesAreLoaded = preloadImage(window)
-			if !imagesAreLoaded {
-				window.DrawText("Loading images...", 0, 0, draw.White)
-				return
-			}
-		}
-
-		if window.WasKeyPressed(draw.KeyEscape) {
-			window.Close()
-		}
-		window.BlurImages(true)
-
-		if nextMusicStart.IsZero() {
-			window.PlaySoundFile(musicIntroFile)
-			nextMusicStart = time.Now().Add(seconds(musicIntroLengthInSeconds))
-		}
-
-		now := time.Now()
-		if now.Equal(nextMusicStart) || now.After(nextMusicStart) {
-			window.PlaySoundFile(musicLoopFile)
-			nextMusicStart = now.Add(seconds(musicLoopLengthInSeconds))
+	var settingsScreen settingsScreen
+	var nameEntry nameEntry
+	var wardrobeScreen wardrobeScreen
+	var debug debugOverlay
+	var photo photoMode
+	// notice is shown at the bottom of the window for noticeFrames frames,
+	// e.g. where the last screenshot went.
+	var notice string
+	var noticeFrames int
+	// flapQueued keeps a flap until the next step, in case a frame has no
+	// step.
+	var flapQueued bool
+
+	drawDressedGopher := func(window renderer, centerX, centerY int, scale float64, frame int, names []string) {
+		const framesPerImage = 8
+		tails := []string{tailCenterImage, tailDownImage, tailCenterImage, tailUpImage}
+		i := (frame / framesPerImage) % len(animationFrames)
+		w, h, _ := window.ImageSize(animationFrames[i])
+		w = round(float64(w) * scale)
+		h = round(float64(h) * scale)
+		x, y := centerX-w/2, centerY-h/2
+		window.DrawImageFileTo(animationFrames[i], x, y, w, h, 0)
+		window.DrawImageFileTo(tails[i], x, y, w, h, 0)
+		for _, a := range accessoryManifest.layers(names) {
+			dx := round(float64(a.OffsetX) * scale)
+			dy := round(float64(a.OffsetY) * scale)
+			window.DrawImageFileTo(a.Image, x+dx, y+dy, w, h, 0)
 		}
+	}
 
-		pipeW, pipeH, _ := window.ImageSize(pipeImage)
-
-		restartable := y > 3*windowH
-
-		if restartable != wasRestartable {
-			killCount++
-			wasRestartable = restartable
-		}
-
-		// Update game state.
-		clickedWithMouse := len(window.Clicks()) > 0
-		clicked := clickedWithMouse ||
-			len(window.Characters()) > 0 ||
-			window.WasKeyPressed(draw.KeyUp) ||
-			window.WasKeyPressed(draw.KeyEnter) ||
-			window.WasKeyPressed(draw.KeyNumEnter)
-
-		if isAlive {
-			hideCursorInFrames--
+	// drawFrame draws the game, or only the world in photo mode. It draws
+	// the window and the screenshots.
+	drawFrame := func(window renderer, restartable bool) {
+		var others []*game
+		if opponent != nil {
+			others = append(others, opponent)
 		}
-		mouseX, mouseY := window.MousePosition()
-		if clickedWithMouse ||
-			mouseX != lastMouseX || mouseY != lastMouseY {
-			hideCursorInFrames = cursorHideTimeout
+		if photo.open {
+			g.drawPanned(window, photo.panX, photo.layers, others...)
+			return
 		}
-		lastMouseX, lastMouseY = mouseX, mouseY
-
-		window.ShowCursor(hideCursorInFrames > 0)
 
-		if restartable && clicked {
-			restart()
-			clicked = false
-		}
+		g.draw(window, others...)
+		g.drawName(window, name)
 
-		if isAlive && clicked {
-			ySpeed = clickYSpeed
-			nextFlapIn = 0
-			needToPlayFlapSound = true
+		scoreH := drawHUD(window, hud{
+			score:              g.score,
+			scoreAnimationTime: g.scoreAnimationTime,
+			highscore:          g.highscore,
+			killCount:          killCount,
+			name:               name,
+			nameAlpha:          g.nameAlpha,
+			playingAlpha:       float32(g.deceasedTextTime) / deceasedTextFadeFrameCount,
+			backgroundColor:    backgroundColor,
+		})
+		if opponent != nil {
+			const opponentScale = 3
+			window.DrawScaledText(tr(msgOpponentScore, opponent.score), 10, 10, opponentScale, draw.Black)
 		}
 
-		flapSoundCoolDown--
+		if restartable {
+			var problems []string
+			if packErr != nil {
+				problems = append(problems, tr(msgPackNotLoaded, packErr))
+			}
+			if accessoryErr != nil {
+				problems = append(problems, tr(msgAccessoriesFailed, accessoryErr))
+			}
+			errY := scoreH
+			for _, text := range problems {
+				const errScale = 2
+				errW, errH := window.GetScaledTextSize(text, errScale)
+				window.DrawScaledText(text, (windowW-errW)/2, errY, errScale, draw.RGB(0.8, 0, 0))
+				errY += errH
+			}
 
-		if needToPlayFlapSound && flapSoundCoolDown <= 0 {
-			window.PlaySoundFile("rsc/flap.wav")
-			flapSoundCoolDown = 30
-		}
+			// Draw the restart instructions.
+			text := restartText(&settings.controls)
+			restartScale := 5 + float32(math.Sin(float64(restartableTime)*0.1))
+			textW, textH := window.GetScaledTextSize(text, restartScale)
+			textX := (windowW - textW) / 2
+			textY := (windowH - textH) / 2
+			window.DrawScaledText(text, textX, textY, restartScale, draw.Black)
 
-		needToPlayFlapSound = false
+			var hints []string
+			for _, a := range []action{actionSettings, actionName, actionWardrobe} {
+				if len(settings.controls[a]) > 0 {
+					hints = append(hints, settings.controls.describe(a)+": "+a.String())
+				}
+			}
+			if len(hints) > 0 {
+				const hintScale = 2
+				hint := strings.Join(hints, "    ")
+				hintW, _ := window.GetScaledTextSize(hint, hintScale)
+				hintY := (windowH+textH)/2 + 10
+				window.DrawScaledText(hint, (windowW-hintW)/2, hintY, hintScale, draw.Black)
+			}
 
-		nextFlapIn--
-		if nextFlapIn <= 0 {
+			// Announce the accessories that the last gopher unlocked, each
+			// with a little gopher wearing it.
 			const (
-				slowestFlapYSpeed = 10.0
-				minFlapIn         = 1
-				maxFlapIn         = 10
+				unlockScale       = 2.5
+				unlockGopherScale = 0.5
 			)
-			relative := (ySpeed - clickYSpeed) / (slowestFlapYSpeed - clickYSpeed)
-			nextFlapIn = round(minFlapIn + relative*(maxFlapIn-minFlapIn))
-			animationIndex = (animationIndex + 1) % len(animationFrames)
-		}
+			unlockGopherW, unlockGopherH, _ := window.ImageSize(deadFrame)
+			unlockGopherW = round(float64(unlockGopherW) * unlockGopherScale)
+			unlockGopherH = round(float64(unlockGopherH) * unlockGopherScale)
+			unlockY := textY - 20 - len(newAccessories)*unlockGopherH
+			for _, a := range newAccessories {
+				unlockText := tr(msgAccessoryUnlocked, a.title())
+				unlockW, unlockH := window.GetScaledTextSize(unlockText, unlockScale)
+				unlockX := (windowW - unlockGopherW - 20 - unlockW) / 2
+				dx := round(float64(a.OffsetX) * unlockGopherScale)
+				dy := round(float64(a.OffsetY) * unlockGopherScale)
+				window.DrawImageFileTo(animationFrames[0], unlockX, unlockY, unlockGopherW, unlockGopherH, 0)
+				window.DrawImageFileTo(tailCenterImage, unlockX, unlockY, unlockGopherW, unlockGopherH, 0)
+				window.DrawImageFileTo(a.Image, unlockX+dx, unlockY+dy, unlockGopherW, unlockGopherH, 0)
+				unlockTextX := unlockX + unlockGopherW + 20
+				unlockTextY := unlockY + (unlockGopherH-unlockH)/2
+				window.DrawScaledText(unlockText, unlockTextX, unlockTextY, unlockScale, draw.RGB(0.5, 0, 0))
+				unlockY += unlockGopherH
+			}
 
-		if gopherXOffset < finalGopherX {
-			// Slide in the gopher into the screen.
-			gopherXOffset = min(gopherXOffset+gopherSpeed, finalGopherX)
+			bottom, width := drawMemorial(window, accessoryManifest, killHistory, g.highscore, g.killScrollY)
+			drawStatistics(window, killHistory, g.highscore, bottom+1, width)
+		}
 
-			if gopherXOffset == finalGopherX {
-				xSpeed = gopherSpeed
+		if debug.open {
+			// Only where the gopher's circle touches a rectangle around a
+			// pipe's solid pixels are the pixels compared.
+			debug.drawCircle(window, gopherCollisionCircle(g.gopherSprite()))
+			for _, gap := range g.gaps {
+				debug.drawRect(window, g.topPipeSprite(gap).solidBounds())
+				debug.drawRect(window, g.bottomPipeSprite(gap).solidBounds())
 			}
-		}
 
-		if gopherXOffset == finalGopherX {
-			nameAlpha = max(nameAlpha-0.33/60.0, 0)
+			lines := []string{
+				fmt.Sprintf("x %.2f, y %.2f", g.x, g.y),
+				fmt.Sprintf("xSpeed %.2f, ySpeed %.2f", g.xSpeed, g.ySpeed),
+				fmt.Sprintf("rotation %.2f, target %.2f", g.rotation, g.targetRotation),
+				fmt.Sprintf("alive %t, score %d, highscore %d", g.isAlive, g.score, g.highscore),
+				fmt.Sprintf("nextGapX %d", g.nextGapX),
+			}
+			for i, gap := range g.gaps {
+				lines = append(lines, fmt.Sprintf(
+					"gap %d: x %d, y %d, shake %d (top %t, bottom %t)",
+					i, gap.centerX, gap.centerY, gap.shakeTimer, gap.topPipeShaking, gap.bottomPipeShaking,
+				))
+			}
+			lines = append(lines,
+				fmt.Sprintf("nextFlapIn %d, flapSoundCoolDown %d", g.nextFlapIn, g.flapSoundCoolDown),
+				fmt.Sprintf("playDeathSoundIn %d, deceasedTextTime %d", g.playDeathSoundIn, g.deceasedTextTime),
+				fmt.Sprintf("scoreAnimationTime %.2f, restartableTime %d", g.scoreAnimationTime, restartableTime),
+				fmt.Sprintf("hideCursorInFrames %d", hideCursorInFrames),
+				fmt.Sprintf("weather %s, gust %d/%d, force %.3f", g.weather.kind.id, g.weather.gust().frame, g.weather.gust().frames, g.weather.force()),
+			)
+			debug.drawState(window, lines)
 		}
 
-		if !isAlive && xSpeed > 0 {
-			xSpeed = max(0, xSpeed-0.15)
+		if nameEntry.open {
+			nameEntry.draw(window)
 		}
+	}
 
-		x += xSpeed
-		for i := range gaps {
-			if gaps[i].centerX-round(x) < -pipeW/2 {
-				gaps[i] = gap{}
-				gaps[i].centerX = nextGapX
-				gaps[i].centerY = randomGapY()
-				nextGapX += gapDistX
-
-				score++
-				window.PlaySoundFile("rsc/score.wav")
-
-				if score > highscore {
-					highscore = score
+	// update runs one frame of the game. The window always has the size
+	// windowW x windowH, see scaledWindow.
+	update := func(window draw.Window) {
+		if !loader.done() {
+			loader.update(window)
+			if loader.err != nil {
+				drawLoadingError(window, loader.err, backgroundColor)
+				if window.WasKeyPressed(draw.KeyEscape) {
+					window.Close()
 				}
-
-				scoreAnimationTime = 1.0
+				return
 			}
-		}
-		y += ySpeed
-		ySpeed += gravity
-
-		wasAlive := isAlive
-
-		if isAlive && y <= -30 {
-			// Drop dead on hitting the ceiling.
-			isAlive = false
-			ySpeed = 0
-			bumpOnHead = true
-			window.PlaySoundFile("rsc/hit_ceiling.wav")
-			playDeathSoundIn = 30
-		}
-		if isAlive && y >= windowH-145 {
-			// Drop dead on hitting the floor. Give it a little upward motion to
-			// make the user see that it is dead.
-			ySpeed = -25
-			isAlive = false
-			window.PlaySoundFile("rsc/hit_floor.wav")
-			playDeathSoundIn = 60
-		}
-
-		// Collide with the pipes.
-		gopherCollisionCircle := func() circle {
-			gopherW, gopherH, _ := window.ImageSize(animationFrames[0])
-			return circle{
-				centerX: gopherXOffset + finalGopherX + gopherW/2,
-				centerY: round(y) + gopherH/2,
-				radius:  gopherCollisionRadius,
+			if !loader.done() {
+				loader.draw(window, backgroundColor)
+				return
 			}
 		}
 
-		topPipeCollisionRect := func(gap gap) rectangle {
-			left := gap.centerX - pipeW/2 - round(x) + 5
-			return rectangle{
-				left:   left,
-				top:    0,
-				right:  left + pipeW - 10,
-				bottom: gap.centerY - gapHeight/2 - 2,
-			}
+		window.BlurImages(true)
+
+		if nextMusicStart.IsZero() {
+			window.PlaySoundFile(musicIntroFile)
+			nextMusicStart = time.Now().Add(seconds(musicIntroLengthInSeconds))
 		}
 
-		bottomPipeCollisionRect := func(gap gap) rectangle {
-			left := gap.centerX - pipeW/2 - round(x) + 5
-			return rectangle{
-				left:   left,
-				top:    gap.centerY + gapHeight/2 + 2,
-				right:  left + pipeW - 10,
-				bottom: windowH,
-			}
+		now := time.Now()
+		if now.Equal(nextMusicStart) || now.After(nextMusicStart) {
+			window.PlaySoundFile(musicLoopFile)
+			nextMusicStart = now.Add(seconds(musicLoopLengthInSeconds))
 		}
 
-		for i := range gaps {
-			gaps[i].shakeTimer--
-		}
-
-		if isAlive {
-			gopher := gopherCollisionCircle()
-			for i, gap := range gaps {
-				top := topPipeCollisionRect(gap)
-				bottom := bottomPipeCollisionRect(gap)
-				topCollides := collides(gopher, top)
-				bottomCollides := collides(gopher, bottom)
-				if topCollides || bottomCollides {
-					isAlive = false
-					window.PlaySoundFile("rsc/hit_pipe.wav")
-					playDeathSoundIn = 25
-					gaps[i].topPipeShaking = topCollides
-					gaps[i].bottomPipeShaking = bottomCollides
-					gaps[i].shakeTimer = pipeShakeFrameCount
-				}
+		if settingsScreen.open {
+			if settingsScreen.update(window, &settings) {
+				saveSettings(settings)
 			}
+			settingsScreen.draw(window, &settings, backgroundColor)
+			window.ShowCursor(true)
+			return
 		}
 
-		playDeathSoundIn--
-		if playDeathSoundIn == 0 {
-			window.PlaySoundFile("rsc/death.wav")
+		if wardrobeScreen.open {
+			stats := statsOf(killHistory)
+			if wardrobeScreen.update(window, accessoryManifest, stats, &settings) {
+				saveSettings(settings)
+			}
+			wardrobeScreen.draw(window, accessoryManifest, stats, &settings, backgroundColor, drawDressedGopher)
+			window.ShowCursor(true)
+			return
 		}
 
-		if wasAlive && !isAlive {
-			killHistory = append(killHistory, kill{
-				Name:        name,
-				Score:       score,
-				Accessories: slices.Clone(accessories),
-			})
-			saveKillHistory(killHistory)
+		// While the player types a name, all keys go into the name entry and
+		// none of them trigger any actions. This includes the frame in which
+		// the name entry is closed with Enter or Escape.
+		typing := nameEntry.open
+		if typing && nameEntry.update(window) {
+			settings.customName = nameEntry.text
+			saveSettings(settings)
 		}
 
-		targetRotation = ySpeed * 1.5
-		rotation = 0.5*targetRotation + 0.5*rotation
-
-		if scoreAnimationTime > 0 {
-			scoreAnimationTime = max(0, scoreAnimationTime-0.05)
+		if !typing && settings.controls.triggered(window, actionQuit) {
+			if photo.open {
+				photo.open = false
+			} else {
+				window.Close()
+			}
 		}
 
-		backgroundW, backgroundH, _ := window.ImageSize(backgroundImages[0])
-		backgroundXDist := backgroundW - 20
-		if len(backgroundTiles) == 0 {
-			// Initialize the background tiles.
-			for i := range 10 {
-				backgroundTiles = append(backgroundTiles, backgroundTile{
-					image:   randomCityImage(),
-					x:       float64((i - 1) * backgroundXDist),
-					yOffset: rand.Intn(150),
-				})
-			}
+		if !typing && settings.controls.triggered(window, actionSettings) {
+			settingsScreen.open = true
+			settingsScreen.capturing = false
+			reloadUserNames()
 		}
 
-		backgroundXOffset := -xSpeed * 0.333
-		for i := range backgroundTiles {
-			backgroundTiles[i].x += backgroundXOffset
+		if !typing && settings.controls.triggered(window, actionDebug) {
+			debug.open = !debug.open
 		}
-		for i := range backgroundTiles {
-			if backgroundTiles[i].x < float64(-backgroundW) {
-				// Respawn this tile after the last background tile.
-				lastTileIndex := (len(backgroundTiles) + i - 1) % len(backgroundTiles)
-				backgroundTiles[i].x = backgroundTiles[lastTileIndex].x + float64(backgroundXDist)
-			}
+		if !typing {
+			debug.update(window)
 		}
 
-		cloudW, cloudH, _ := window.ImageSize(cloudImage)
-		baseCloudSpeed := -xSpeed * 0.2
-		for i := range clouds {
-			clouds[i].x += baseCloudSpeed * clouds[i].scale
-			if clouds[i].x < float64(-cloudW) {
-				clouds[i].x = windowW
-				clouds[i].scale = randomCloudScale()
-				clouds[i].y = randomCloudY()
+		// The photo mode freezes the game, it ignores flaps and does not
+		// step.
+		if !typing && settings.controls.triggered(window, actionPhoto) {
+			if photo.open {
+				photo.open = false
+			} else {
+				photo.start(g)
 			}
 		}
+		if photo.open {
+			photo.update(window)
+		}
 
-		nameAnimationTime++
+		g.accessoryCollision = settings.accessoryCollision
+		restartable := g.restartable()
 
-		if !isAlive && deceasedTextTime > 0 {
-			deceasedTextTime--
+		if restartable != wasRestartable {
+			killCount++
+			wasRestartable = restartable
 		}
 
-		if restartable {
-			killScrollY--
-		}
+		// Update game state.
+		clickedWithMouse := len(window.Clicks()) > 0
+		clicked := !typing && !photo.open && settings.controls.triggered(window, actionFlap)
 
-		// Draw game.
+		if g.isAlive {
+			hideCursorInFrames--
+		}
+		mouseX, mouseY := window.MousePosition()
+		if clickedWithMouse ||
+			mouseX != lastMouseX || mouseY != lastMouseY {
+			hideCursorInFrames = cursorHideTimeout
+		}
+		lastMouseX, lastMouseY = mouseX, mouseY
 
-		window.FillRect(0, 0, 9999, 9999, backgroundColor)
+		window.ShowCursor(hideCursorInFrames > 0)
 
-		for _, cloud := range clouds {
-			x := round(cloud.x)
-			w := round(float64(cloudW) * cloud.scale)
-			h := round(float64(cloudH) * cloud.scale)
-			window.DrawImageFileTo(cloudImage, x, cloud.y, w, h, 0)
+		if restartable && !typing && !photo.open && settings.controls.triggered(window, actionName) {
+			nameEntry.start(settings.customName)
 		}
 
-		for _, tile := range backgroundTiles {
-			tileX := round(tile.x)
-			tileY := windowH - backgroundH + tile.yOffset
-			window.DrawImageFile(tile.image, tileX, tileY)
+		if restartable && !typing && !photo.open && settings.controls.triggered(window, actionWardrobe) {
+			wardrobeScreen.open = true
 		}
 
-		for _, gap := range gaps {
-			gapX := gap.centerX - pipeW/2 - round(x)
+		if restartable && clicked {
+			restart()
+			clicked = false
+		}
 
-			rotation := 0
-			if gap.shakeTimer > 0 {
-				amplitude := 7 * float64(gap.shakeTimer) / pipeShakeFrameCount
-				t := float64(pipeShakeFrameCount - gap.shakeTimer)
-				rotation = round(math.Sin(t*0.8) * amplitude)
+		flapQueued = flapQueued || clicked
+		if !photo.open && debug.shouldStep() {
+			g.step(flapQueued)
+			flapQueued = false
+			if opponent != nil {
+				opponent.step(opponentNetwork.flap(opponent.observe()))
 			}
-
-			// Bottom pipe.
-			bottomRotation := 0
-			if gap.bottomPipeShaking && gap.shakeTimer > 0 {
-				bottomRotation = rotation
+			for _, sound := range g.sounds {
+				window.PlaySoundFile(sound)
 			}
-			bottomY := gap.centerY + gapHeight/2
-			window.DrawImageFileRotated(pipeImage, gapX, bottomY, bottomRotation)
-
-			// Top pipe.
-			topRotation := 0
-			if gap.topPipeShaking && gap.shakeTimer > 0 {
-				topRotation = rotation
+			if g.died {
+				statsBefore := statsOf(killHistory)
+				killHistory = append(killHistory, g.kill(name, nameSource))
+				saveKillHistory(killHistory)
+				newAccessories = accessoryManifest.newlyUnlocked(statsBefore, statsOf(killHistory))
 			}
-			topY := gap.centerY - gapHeight/2 - pipeH
-			window.DrawImageFileRotated(pipeImage, gapX, topY, 180+topRotation)
-		}
-
-		// Render the gopher.
-		gopherImage := deadFrame
-		if isAlive {
-			gopherImage = animationFrames[animationIndex]
-		} else if bumpOnHead {
-			gopherImage = bumpFrame
-		}
-
-		tail := tailCenterImage
-		if ySpeed > 7 {
-			tail = tailUpImage
-		}
-		if ySpeed < -7 {
-			tail = tailDownImage
-		}
-		if !isAlive {
-			tail = tailDownImage
-		}
-
-		gopherX, gopherY := gopherXOffset+finalGopherX, round(y)
-		gopherRotation := round(rotation)
-		window.DrawImageFileRotated(gopherImage, gopherX, gopherY, gopherRotation)
-		window.DrawImageFileRotated(tail, gopherX, gopherY, gopherRotation)
-		for _, a := range accessories {
-			img := "rsc/" + a + ".png"
-			window.DrawImageFileRotated(img, gopherX, gopherY, gopherRotation)
-		}
-
-		// Render the animated name above the gopher's head.
-		gopherW, _, _ := window.ImageSize(gopherImage)
-		const headNameScale = 4
-		headNameW, headNameH := window.GetScaledTextSize(name, headNameScale)
-		headNameX := gopherXOffset + finalGopherX + gopherW/2 - headNameW/2
-		headNameY := gopherY - headNameH
-		runeW, _ := window.GetScaledTextSize("x", headNameScale)
-		runeX := headNameX
-		runeI := 0
-		for _, r := range name {
-			yOffset := (math.Sin(0.5*float64(runeI)+0.075*float64(nameAnimationTime)) + 1) / 2
-			runeY := headNameY - round(yOffset*0.75*float64(headNameH))
-			window.DrawScaledText(string(r), runeX, runeY, headNameScale, draw.RGBA(0, 0, 0, nameAlpha))
-			runeX += runeW
-			runeI++
-		}
-
-		textBackgroundColor := backgroundColor
-		textBackgroundColor.A = 0.6
-		const textBorderSize = 5
-
-		const highscoreScale = 4
-		highscoreText := fmt.Sprintf(" Highscore %d ", highscore)
-		highscoreW, highscoreH := window.GetScaledTextSize(highscoreText, highscoreScale)
-		highscoreX := windowW - highscoreW
-		highscoreYMargin := 10
-		highscoreY := highscoreYMargin
-		highscoreBottom := highscoreY + highscoreH + highscoreYMargin
-		// Fill the text background.
-		window.FillRect(highscoreX, 0, 9999, highscoreBottom, textBackgroundColor)
-		// Create a fuzzy border around the text background.
-		textBorderColor := textBackgroundColor
-		for i := range textBorderSize - 1 {
-			textBorderColor.A -= textBackgroundColor.A / float32(textBorderSize)
-			window.FillRect(highscoreX-1-i, 0, 1, highscoreBottom+i, textBorderColor)
-			window.FillRect(highscoreX-1-i, highscoreBottom+i, 9999, 1, textBorderColor)
-		}
-		// Draw the text on top of the background.
-		window.DrawScaledText(highscoreText, highscoreX, highscoreY, highscoreScale, draw.Black)
-
-		// We now draw the gopher name and the kill count in the bottom right
-		// hand corner. We want to surround both of these with a single text
-		// background rectangle. That is why we do the text size and position
-		// calculations first, then draw the background, then draw the texts on
-		// top of it.
-		const killTextYMargin = 10
-		const killScale = 2
-		killSuffix := "s"
-		if killCount == 1 {
-			killSuffix = ""
-		}
-		killText := fmt.Sprintf(" %d dead gopher%s so far ", killCount, killSuffix)
-		killW, killH := window.GetScaledTextSize(killText, killScale)
-		killX := windowW - killW
-		killY := windowH - killH - killTextYMargin
-
-		// Render the name above the kill count.
-		// We blend the text "now playing ..." and "recently deceased ..." when
-		// the gopher goes from alive to dead. That is why we render both texts
-		// always, but with a different opacity.
-		const nameScale = 2
-		playingTextAlpha := float32(deceasedTextTime) / deceasedTextFadeFrameCount
-
-		aliveNameText := " now playing: " + name + " "
-		aliveNameW, aliveNameH := window.GetScaledTextSize(aliveNameText, nameScale)
-		aliveNameX := windowW - aliveNameW
-		aliveNameY := killY - aliveNameH
-		aliveNameAlpha := (1 - nameAlpha) * playingTextAlpha
-
-		deadNameText := " recently deceased: " + name + " "
-		deadNameW, deadNameH := window.GetScaledTextSize(deadNameText, nameScale)
-		deadNameX := windowW - deadNameW
-		deadNameY := killY - deadNameH
-		deadNameAlpha := (1 - nameAlpha) * (1 - playingTextAlpha)
-
-		// Create the text background.
-		backX := killX
-		if aliveNameAlpha > 0 && aliveNameX < backX {
-			backX = aliveNameX
-		}
-		if deadNameAlpha > 0 && deadNameX < backX {
-			backX = deadNameX
-		}
-		backY := killY - killTextYMargin
-		if aliveNameAlpha > 0 || deadNameAlpha > 0 {
-			backY = deadNameY - killTextYMargin
-		}
-		// Fill the text background.
-		window.FillRect(backX, backY, 9999, 9999, textBackgroundColor)
-		// Create a fuzzy border around the text background.
-		textBorderColor = textBackgroundColor
-		for i := range textBorderSize - 1 {
-			textBorderColor.A -= textBackgroundColor.A / float32(textBorderSize)
-			window.FillRect(backX-1-i, backY-i, 1, 9999, textBorderColor)
-			window.FillRect(backX-1-i, backY-1-i, 9999, 1, textBorderColor)
-		}
-
-		// Write the texts on top of the background.
-		window.DrawScaledText(aliveNameText, aliveNameX, aliveNameY, nameScale, draw.RGBA(0, 0, 0, aliveNameAlpha))
-		window.DrawScaledText(deadNameText, deadNameX, deadNameY, nameScale, draw.RGBA(0, 0, 0, deadNameAlpha))
-		window.DrawScaledText(killText, killX, killY, killScale, draw.RGB(0.7, 0, 0))
-
-		const (
-			regularScoreScale = 7.0
-			maxScoreScale     = 12.0
-		)
-		scoreScale := float32(regularScoreScale)
-		if scoreAnimationTime > 0 {
-			scoreArc := (math.Sin(1.5*math.Pi+2*math.Pi*scoreAnimationTime) + 1) * 0.5
-			scoreScale = float32(regularScoreScale + scoreArc*(maxScoreScale-regularScoreScale))
-		}
-		scoreText := fmt.Sprintf(" %d ", score)
-		scoreW, _ := window.GetScaledTextSize(scoreText, scoreScale)
-		scoreX := (windowW - scoreW) / 2
-		window.DrawScaledText(scoreText, scoreX, 0, scoreScale, draw.Black)
+		}
+
+		// Draw game.
 
+		drawFrame(window, restartable)
 		if restartable {
-			// Draw the restart instructions.
-			const text = "Click to Restart"
-			restartScale := 5 + float32(math.Sin(float64(restartableTime)*0.1))
 			restartableTime++
-			textW, textH := window.GetScaledTextSize(text, restartScale)
-			textX := (windowW - textW) / 2
-			textY := (windowH - textH) / 2
-			window.DrawScaledText(text, textX, textY, restartScale, draw.Black)
-
-			// Draw the kill history.
-			longestNameCharCount := 0
-			for _, k := range killHistory {
-				longestNameCharCount = max(longestNameCharCount, utf8.RuneCountInString(k.Name))
-			}
-
-			eulogies := []string{
-				"Our beloved %s passed after %d pipe%s",
-				"%s left us peacefully after %d pipe%s",
-				"Our dear friend %s passed after %d pipe%s",
-				"%s passed quietly after %d pipe%s",
-				"In loving memory of %s who cleared %d pipe%s",
-				"Dear %s died after %d cleared pipe%s",
-			}
-
-			const textScale = 2.5
-
-			longestEulogyW := 0
-			for _, e := range eulogies {
-				text := fmt.Sprintf(e, strings.Repeat("A", longestNameCharCount), highscore, "s")
-				textW, _ := window.GetScaledTextSize(text, textScale)
-				longestEulogyW = max(longestEulogyW, textW)
-			}
-
-			gopherW, gopherH, _ := window.ImageSize(deadFrame)
-			const gopherScale = 0.33
-			gopherW = round(float64(gopherW) * gopherScale)
-			gopherH = round(float64(gopherH) * gopherScale)
-
-			backgroundW := 4*gopherW + longestEulogyW
-
-			alphaAtY := func(y int) float32 {
-				dy := abs(windowH/2 - y)
-				alpha := float32(dy-40) / 90
-				return max(0.1, min(0.95, alpha))
-			}
-
-			y := windowH + 280 + killScrollY
-
-			const captionScale = nameScale * 2
-
-			writeCaption := func(caption string, top, bottom int) {
-				captionW, captionH := window.GetScaledTextSize(caption, captionScale)
-				captionY := (top + bottom - captionH) / 2
-				captionAlpha := alphaAtY(captionY + captionH/2)
-				topAlpha := alphaAtY(top)
-				bottomAlpha := alphaAtY(bottom)
-				if topAlpha == bottomAlpha {
-					window.FillRect((windowW-backgroundW)/2, top, backgroundW, bottom-top+1, draw.RGBA(1, 1, 1, topAlpha))
-				} else {
-					for y := top; y <= bottom; y++ {
-						a := alphaAtY(y)
-						window.FillRect((windowW-backgroundW)/2, y, backgroundW, 1, draw.RGBA(1, 1, 1, a))
-					}
-				}
-				window.DrawScaledText(caption, (windowW-captionW)/2, captionY, captionScale, draw.RGBA(0.5, 0, 0, captionAlpha))
-			}
-
-			const captionHeight = 180
-
-			caption := "In Honor of our Hero"
-			if len(killHistory) != 1 {
-				caption += "s"
-			}
-			writeCaption(caption, y-captionHeight, y-1)
-
-			for i := len(killHistory) - 1; i >= 0; i-- {
-				if -gopherH < y && y < windowH {
-					kill := killHistory[i]
-
-					lineCenterY := y + gopherH/2
-
-					alpha := alphaAtY(lineCenterY)
-
-					top := y
-					bottom := y + gopherH - 1
-					topAlpha := alphaAtY(top)
-					bottomAlpha := alphaAtY(bottom)
-
-					if topAlpha == bottomAlpha {
-						window.FillRect((windowW-backgroundW)/2, y, backgroundW, gopherH, draw.RGBA(1, 1, 1, alpha))
-					} else {
-						for y := top; y <= bottom; y++ {
-							a := alphaAtY(y)
-							window.FillRect((windowW-backgroundW)/2, y, backgroundW, 1, draw.RGBA(1, 1, 1, a))
-						}
-					}
-
-					pluralS := "s"
-					if kill.Score == 1 {
-						pluralS = ""
-					}
-					text := fmt.Sprintf(eulogies[i%len(eulogies)], kill.Name, kill.Score, pluralS)
-
-					textW, textH := window.GetScaledTextSize(text, textScale)
-					textOffsetY := (gopherH - textH) / 2
-
-					leftX := (windowW - 2*gopherW - textW) / 2
-
-					drawGopherAtX := func(x, xScale int) {
-						window.DrawImageFileTo(deadFrame, x, y, gopherW*xScale, gopherH, 0)
-						for _, a := range kill.Accessories {
-							window.DrawImageFileTo("rsc/"+a+".png", x, y, gopherW*xScale, gopherH, 0)
-						}
-						window.DrawImageFileTo(tailDownImage, x, y, gopherW*xScale, gopherH, 0)
-					}
-
-					drawGopherAtX(leftX, 1)
-
-					// Draw the hero's name.
-					textX := leftX + gopherW*3/2
-					textY := y + textOffsetY
-					window.DrawScaledText(text, textX, textY, textScale, draw.RGBA(0.5, 0, 0, alpha))
-
-					drawGopherAtX(textX+textW+gopherW+gopherW/2, -1)
-				}
+		}
 
-				y += gopherH
+		if !typing && settings.controls.triggered(window, actionScreenshot) {
+			shot := newSoftwareRenderer(windowW, windowH)
+			drawFrame(shot, restartable)
+			path, err := saveScreenshot(shot.image, "flappy_"+fileName(name, g.score))
+			notice = tr(msgScreenshotSaved, path)
+			if err != nil {
+				notice = tr(msgScreenshotFailed, err)
 			}
+			noticeFrames = 180
+		}
 
-			writeCaption("You will be missed", y, y+captionHeight)
-
-			minGraphY := windowH/2 + 80
-			statisticsY := max(minGraphY, y+captionHeight+1)
-			if len(killHistory) >= 2 && statisticsY < windowH {
-				graphBackColor := draw.RGBA(1, 1, 1, 0.9)
-				graphForeColor := draw.RGBA(0, 0, 0, 0.9)
-				graphX := (windowW - backgroundW) / 2
-				graphY := statisticsY
-				graphW := backgroundW
-				graphH := windowH - 20 - minGraphY
-				const graphMarginTop = 120
-				const graphMarginBottom = 20
-				const graphMarginLeft = 30
-				const graphMarginRight = 30
-				innerGraphH := graphH - graphMarginTop - graphMarginBottom
-				zeroY := graphY + graphH - graphMarginBottom
-				highestX := 0
-				highestY := 0
-				highestName := ""
-
-				leftX := windowW/2 - 10*len(killHistory)
-				rightX := windowW/2 + 10*len(killHistory)
-				leftX = max(leftX, graphX+graphMarginLeft+1)
-				rightX = min(rightX, graphX+graphW-graphMarginRight-1)
-
-				window.FillRect(graphX, graphY, graphW, graphH, graphBackColor)
-
-				const captionScale = 2.5
-				caption := "Pipe Smoking Statistics"
-				captionW, _ := window.GetScaledTextSize(caption, captionScale)
-				captionX := graphX + (graphW-captionW)/2
-				captionY := graphY + 5
-				window.DrawScaledText(caption, captionX, captionY, captionScale, graphForeColor)
-
-				for i, k := range killHistory {
-					x := leftX + round(float64(i)/float64(len(killHistory)-1)*float64(rightX-leftX-1))
-
-					y := graphY + graphH - graphMarginBottom
-					if highscore > 0 {
-						y -= round(float64(k.Score) * float64(innerGraphH) / float64(highscore))
-					}
-
-					window.FillRect(x-1, y-1, 3, zeroY-y+1, graphForeColor)
-
-					if k.Score == highscore {
-						highestName = k.Name
-						highestX, highestY = x, y
-					}
-				}
-
-				window.FillRect(leftX-1, zeroY, rightX-leftX+2, 1, graphForeColor)
-
-				const textScale = 1.5
-
-				text := fmt.Sprintf("%s cleared %d pipes", highestName, highscore)
-				textW, textH := window.GetScaledTextSize(text, textScale)
-				textX := highestX - textW/2
-				textY := highestY - textH - 10
-				textX = max(textX, graphX+graphMarginLeft)
-				textX = min(textX, graphX+graphW-graphMarginRight-textW)
-				window.DrawScaledText(text, textX, textY, textScale, graphForeColor)
-			}
+		if photo.open {
+			photo.drawHelp(window, &settings.controls)
+		}
+		if noticeFrames > 0 {
+			noticeFrames--
+			drawNotice(window, notice)
 		}
+	}
 
+	screen := newScaledWindow(windowW, windowH)
+	startedFullscreen := false
+	draw.RunWindow("Flappy Go", windowW, windowH, func(window draw.Window) {
+		window.SetIcon("rsc/icon.png")
+		if settings.fullscreen && !startedFullscreen {
+			window.SetFullscreen(true)
+			startedFullscreen = true
+		}
+
+		// Alt+Enter always toggles fullscreen, the other bindings can be
+		// changed in the settings.
+		altDown := window.IsKeyDown(draw.KeyLeftAlt) || window.IsKeyDown(draw.KeyRightAlt)
+		altEnter := altDown && (window.WasKeyPressed(draw.KeyEnter) || window.WasKeyPressed(draw.KeyNumEnter))
+		capturing := settingsScreen.open && settingsScreen.capturing
+		if altEnter || !capturing && !nameEntry.open && settings.controls.triggered(window, actionFullscreen) {
+			settings.fullscreen = !window.IsFullscreen()
+			window.SetFullscreen(settings.fullscreen)
+			saveSettings(settings)
+		}
+
+		// Enter also flaps, but not together with Alt.
+		screen.fit(window)
+		screen.blockKeys = altEnter
+		debug.startFrame()
+		update(screen)
+		screen.drawBorders()
+		debug.endFrame()
 	})
 }
 
@@ -915,34 +565,92 @@ type rectangle struct {
 	bottom int
 }
 
-type backgroundTile struct {
-	image   string
-	x       float64
-	yOffset int
+type kill struct {
+	Name string
+	// NameSource is the id of the nameSource that the name was taken from.
+	NameSource  string
+	Score       int
+	Accessories []string
+	// Weather is the id of the weather during the run, empty for runs from
+	// older versions of the game.
+	Weather string
+	// Replay has what it takes to play the run again, nil for runs from older
+	// versions of the game.
+	Replay *replay
 }
 
-type cloud struct {
-	scale float64
-	x     float64
-	y     int
+// replay is the input of a run. The game is deterministic, so replaying the
+// flaps in a game reset with the same seed and accessories repeats the run.
+type replay struct {
+	Seed int64
+	// Flaps are the steps in which the gopher flapped, counted from the start
+	// of the run.
+	Flaps              []int
+	AccessoryCollision bool
+	// Gaps is the gap pattern, see gapPatterns. Runs from before the patterns
+	// have none, they used the unchecked pattern.
+	Gaps string
 }
 
-type kill struct {
-	Name        string
-	Score       int
-	Accessories []string
+// flapsIn reports whether the gopher flapped in the given step of the run.
+func (r *replay) flapsIn(step int) bool {
+	_, found := slices.BinarySearch(r.Flaps, step)
+	return found
+}
+
+// start resets the game to the start of the run. Then call
+// g.step(r.flapsIn(g.steps)) to replay it step by step.
+func (r *replay) start(g *game, accessories []string) {
+	g.gapPattern = r.Gaps
+	if g.gapPattern == "" {
+		g.gapPattern = uncheckedGapPattern
+	}
+	g.reset(r.Seed, accessories)
+	g.accessoryCollision = r.AccessoryCollision
 }
 
 func killsToBytes(kills []kill) []byte {
 	var buf bytes.Buffer
 	for _, k := range kills {
-		buf.WriteString(k.Name)
+		buf.WriteString(escapeField(k.Name))
 		buf.WriteString(" ")
 		buf.WriteString(strconv.Itoa(k.Score))
 		for _, a := range k.Accessories {
 			buf.WriteString(" ")
 			buf.WriteString(a)
 		}
+		// Additional information is stored as key=value pairs behind the
+		// accessories. Accessory names never contain a '='.
+		if k.NameSource != "" {
+			buf.WriteString(" names=")
+			buf.WriteString(k.NameSource)
+		}
+		if k.Weather != "" {
+			buf.WriteString(" weather=")
+			buf.WriteString(k.Weather)
+		}
+		if k.Replay != nil {
+			buf.WriteString(" seed=")
+			buf.WriteString(strconv.FormatInt(k.Replay.Seed, 10))
+			// The flaps are stored as the steps between them, which keeps the
+			// numbers short.
+			buf.WriteString(" flaps=")
+			last := 0
+			for i, step := range k.Replay.Flaps {
+				if i > 0 {
+					buf.WriteString(",")
+				}
+				buf.WriteString(strconv.Itoa(step - last))
+				last = step
+			}
+			if k.Replay.AccessoryCollision {
+				buf.WriteString(" accessoryCollision=true")
+			}
+			if k.Replay.Gaps != "" {
+				buf.WriteString(" gaps=")
+				buf.WriteString(k.Replay.Gaps)
+			}
+		}
 		buf.WriteString("\n")
 	}
 	return buf.Bytes()
@@ -953,19 +661,79 @@ func bytesToKills(data []byte) []kill {
 	for line := range strings.SplitSeq(string(data), "\n") {
 		cols := strings.Split(line, " ")
 		if len(cols) >= 2 {
-			name := cols[0]
-			score, _ := strconv.Atoi(cols[1])
-			accessories := cols[2:]
-			kills = append(kills, kill{
-				Name:        name,
-				Score:       score,
-				Accessories: accessories,
-			})
+			k := kill{
+				Name:       unescapeField(cols[0]),
+				NameSource: defaultNameSource,
+			}
+			k.Score, _ = strconv.Atoi(cols[1])
+			for _, col := range cols[2:] {
+				if key, value, ok := strings.Cut(col, "="); ok {
+					switch key {
+					case "names":
+						k.NameSource = value
+					case "weather":
+						k.Weather = value
+					case "seed":
+						seed, err := strconv.ParseInt(value, 10, 64)
+						if err == nil {
+							k.replay().Seed = seed
+						}
+					case "flaps":
+						step := 0
+						for delta := range strings.SplitSeq(value, ",") {
+							if d, err := strconv.Atoi(delta); err == nil {
+								step += d
+								k.replay().Flaps = append(k.replay().Flaps, step)
+							}
+						}
+					case "accessoryCollision":
+						k.replay().AccessoryCollision = value == "true"
+					case "gaps":
+						k.replay().Gaps = value
+					}
+				} else {
+					k.Accessories = append(k.Accessories, col)
+				}
+			}
+			kills = append(kills, k)
 		}
 	}
 	return kills
 }
 
+// replay returns the kill's replay, creating it if it has none yet.
+func (k *kill) replay() *replay {
+	if k.Replay == nil {
+		k.Replay = &replay{}
+	}
+	return k.Replay
+}
+
+// escapeField makes s safe to store in a space-separated line of text, i.e. it
+// escapes spaces, new lines and the percent sign. All other characters, like
+// the letters of Japanese names, are stored as they are, which keeps the files
+// readable.
+func escapeField(s string) string {
+	return fieldEscaper.Replace(s)
+}
+
+var fieldEscaper = strings.NewReplacer(
+	"%", "%25",
+	" ", "%20",
+	"\t", "%09",
+	"\n", "%0A",
+	"\r", "%0D",
+)
+
+// unescapeField undoes escapeField. It also reads the fields of older
+// versions, which escaped all characters that do not belong in a URL path.
+func unescapeField(s string) string {
+	if unescaped, err := url.PathUnescape(s); err == nil {
+		return unescaped
+	}
+	return s
+}
+
 func collides(c circle, r rectangle) bool {
 	closestX := min(r.right, max(r.left, c.centerX))
 	closestY := min(r.bottom, max(r.top, c.centerY))
@@ -1002,6 +770,16 @@ func seconds(s float64) time.Duration {
 	return time.Duration(round(s * float64(time.Second)))
 }
 
+// rotateOffset rotates the vector x,y clockwise by the given angle.
+func rotateOffset(x, y, degrees int) (int, int) {
+	if x == 0 && y == 0 {
+		return 0, 0
+	}
+	sin, cos := math.Sincos(float64(degrees) * math.Pi / 180)
+	fx, fy := float64(x), float64(y)
+	return round(fx*cos - fy*sin), round(fx*sin + fy*cos)
+}
+
 func round(x float64) int {
 	if x < 0 {
 		return int(x - 0.5)
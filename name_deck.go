@@ -0,0 +1,142 @@
+package main
+
+import (
+	"hash/crc32"
+	"math/rand"
+	"slices"
+	"strings"
+)
+
+// nameDeck hands out names from a name list in random order. We use up all
+// names first, then re-shuffle the list and re-use names. This might happen
+// many times. Each deck is shuffled deterministically with its own seed: seed 0
+// for the first deck, seed 1 for the second, seed 2 for the third and so on.
+//
+// Every name source has its own deck. It is stored in the settings as its seed,
+// the position of the next name and the names dealt so far, so we can re-create
+// it between two runs of the program. The order of the names in a deck relies on the random number
+// generation being the same, which is promised by Go, and on the name list
+// being the same. To notice changes to the name list, we also store a checksum
+// of it. If the list changes, the names that were already dealt from the
+// current deck are retired for the rest of the deck, so no name repeats until
+// all names were used.
+type nameDeck struct {
+	seed     int64
+	position int
+	checksum uint32
+	// retired names are not part of the current deck. They were dealt from an
+	// older version of the name list before it changed.
+	retired []string
+	// dealt are the names that were dealt from the current deck since the
+	// list last changed, the first position names of its order.
+	dealt []string
+}
+
+// legacyNameDeck creates the deck that older versions of the game used after
+// the given number of kills. They derived the deck from the length of the kill
+// history and only had the English name list.
+func legacyNameDeck(killCount int, names []string) *nameDeck {
+	return &nameDeck{
+		seed:     int64(killCount / len(names)),
+		position: killCount % len(names),
+		checksum: nameListChecksum(names),
+	}
+}
+
+func nameListChecksum(names []string) uint32 {
+	return crc32.ChecksumIEEE([]byte(strings.Join(names, "\n")))
+}
+
+// order returns all names of the current deck in the order they are dealt.
+func (d *nameDeck) order(names []string) []string {
+	deck := slices.Clone(names)
+	if len(d.retired) > 0 {
+		retired := make(map[string]bool)
+		for _, name := range d.retired {
+			retired[name] = true
+		}
+		deck = slices.DeleteFunc(deck, func(name string) bool {
+			return retired[name]
+		})
+	}
+	rng := rand.New(rand.NewSource(d.seed))
+	shuffleStrings(rng, deck)
+	return deck
+}
+
+// update adjusts the deck to a changed name list.
+func (d *nameDeck) update(names []string) {
+	checksum := nameListChecksum(names)
+	if checksum == d.checksum {
+		// Decks of older versions only know their position.
+		if len(d.dealt) != d.position {
+			deck := d.order(names)
+			d.dealt = slices.Clone(deck[:min(d.position, len(deck))])
+		}
+		return
+	}
+
+	// The names that were dealt from the current deck are retired until the
+	// deck is used up. Names that are no longer in the list do not matter.
+	inList := make(map[string]bool)
+	for _, name := range names {
+		inList[name] = true
+	}
+	var retired []string
+	for _, name := range slices.Concat(d.retired, d.dealt) {
+		if inList[name] && !slices.Contains(retired, name) {
+			retired = append(retired, name)
+		}
+	}
+
+	d.position = 0
+	d.checksum = checksum
+	d.retired = retired
+	d.dealt = nil
+	if len(retired) == len(names) {
+		d.nextDeck()
+	}
+}
+
+func (d *nameDeck) nextDeck() {
+	d.seed++
+	d.position = 0
+	d.retired = nil
+	d.dealt = nil
+}
+
+// next deals the next name from the deck. names must be the list that the deck
+// was last updated with.
+func (d *nameDeck) next(names []string) string {
+	deck := d.order(names)
+	if d.position >= len(deck) {
+		d.nextDeck()
+		deck = d.order(names)
+	}
+
+	name := deck[d.position]
+	d.position++
+	d.dealt = append(d.dealt, name)
+	return name
+}
+
+// randomName deals the next name from the player's name source. The decks are
+// stored in the settings, which are saved afterwards.
+func randomName(settings settings, killHistory []kill) (name, source string) {
+	nameSource := findNameSource(settings.nameSource)
+	names := nameSource.names()
+
+	deck := settings.nameDecks[nameSource.id()]
+	if deck == nil {
+		deck = &nameDeck{checksum: nameListChecksum(names)}
+		if nameSource.id() == defaultNameSource {
+			deck = legacyNameDeck(len(killHistory), names)
+		}
+		settings.nameDecks[nameSource.id()] = deck
+	}
+	deck.update(names)
+
+	name = deck.next(names)
+	saveSettings(settings)
+	return name, nameSource.id()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func testNames(n int) []string {
+	names := make([]string, n)
+	for i := range names {
+		names[i] = fmt.Sprintf("name%d", i)
+	}
+	return names
+}
+
+func TestNameDeckDealsEveryNameOnce(t *testing.T) {
+	names := testNames(50)
+	d := &nameDeck{checksum: nameListChecksum(names)}
+	for deck := range 3 {
+		dealt := make(map[string]bool)
+		for range names {
+			name := d.next(names)
+			if dealt[name] {
+				t.Fatalf("deck %d dealt %s twice", deck, name)
+			}
+			dealt[name] = true
+		}
+		if d.seed != int64(deck) {
+			t.Fatalf("deck %d has seed %d", deck, d.seed)
+		}
+	}
+}
+
+func TestNameDeckContinuesAfterLoading(t *testing.T) {
+	names := testNames(20)
+	d := &nameDeck{checksum: nameListChecksum(names)}
+	for range 27 {
+		d.next(names)
+	}
+
+	s := defaultSettings()
+	s.nameDecks["test"] = d
+	loaded := bytesToSettings(settingsToBytes(s)).nameDecks["test"]
+	if loaded == nil {
+		t.Fatal("the deck was not loaded")
+	}
+	loaded.update(names)
+	for i := range 30 {
+		want, have := d.next(names), loaded.next(names)
+		if want != have {
+			t.Fatalf("name %d after loading is %s instead of %s", i, have, want)
+		}
+	}
+}
+
+func TestNameDeckRetiresDealtNamesWhenTheListChanges(t *testing.T) {
+	names := testNames(20)
+	d := &nameDeck{seed: 4, checksum: nameListChecksum(names)}
+	var recent []string
+	for range 8 {
+		recent = append(recent, d.next(names))
+	}
+
+	// Two names were removed and three were added.
+	changed := slices.Concat(
+		slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+			return name == recent[0] || name == "name19"
+		}),
+		[]string{"new0", "new1", "new2"},
+	)
+	d.update(changed)
+	if d.checksum != nameListChecksum(changed) || d.position != 0 {
+		t.Fatalf("the deck was not updated: %+v", d)
+	}
+	if d.seed != 4 {
+		t.Fatalf("the deck changed its seed to %d", d.seed)
+	}
+	var stillListed []string
+	for _, name := range recent {
+		if slices.Contains(changed, name) {
+			stillListed = append(stillListed, name)
+		}
+	}
+	if !slices.Equal(d.retired, stillListed) {
+		t.Fatalf("retired %v, want the dealt names that are still in the list %v", d.retired, stillListed)
+	}
+
+	dealt := make(map[string]bool)
+	for _, name := range recent {
+		dealt[name] = true
+	}
+	// The rest of the deck has all names that were not dealt yet.
+	rest := len(changed) - len(d.retired)
+	for range rest {
+		name := d.next(changed)
+		if dealt[name] {
+			t.Fatalf("%s was dealt again before the deck was used up", name)
+		}
+		if !slices.Contains(changed, name) {
+			t.Fatalf("%s is not in the changed list", name)
+		}
+		dealt[name] = true
+	}
+	for _, name := range changed {
+		if !dealt[name] {
+			t.Fatalf("%s was never dealt", name)
+		}
+	}
+
+	// The next deck has all names again.
+	d.next(changed)
+	if d.seed != 5 || len(d.retired) != 0 {
+		t.Fatalf("the next deck is %+v", d)
+	}
+}
+
+func TestNameDeckUpdateKeepsAnUnchangedList(t *testing.T) {
+	names := testNames(10)
+	d := &nameDeck{seed: 2, position: 3, checksum: nameListChecksum(names)}
+	d.update(names)
+	if d.seed != 2 || d.position != 3 || len(d.retired) != 0 {
+		t.Fatalf("an unchanged list changed the deck to %+v", d)
+	}
+}
+
+func TestNameDeckRetiresTheWholeList(t *testing.T) {
+	names := testNames(5)
+	d := &nameDeck{checksum: nameListChecksum(names)}
+	var recent []string
+	for range 4 {
+		recent = append(recent, d.next(names))
+	}
+	// Only the dealt names remain, so the next deck starts.
+	d.update(recent)
+	if d.seed != 1 || d.position != 0 || len(d.retired) != 0 {
+		t.Fatalf("the deck is %+v", d)
+	}
+}
+
+func TestNameDeckRemembersDealtNamesAfterLoading(t *testing.T) {
+	names := testNames(20)
+	d := &nameDeck{checksum: nameListChecksum(names)}
+	var dealt []string
+	for range 6 {
+		dealt = append(dealt, d.next(names))
+	}
+
+	// The gophers with these names might never have died, e.g. because the
+	// player quit, so the deck itself has to know them.
+	s := defaultSettings()
+	s.nameDecks["test"] = d
+	loaded := bytesToSettings(settingsToBytes(s)).nameDecks["test"]
+	if loaded == nil {
+		t.Fatal("the deck was not loaded")
+	}
+	changed := append(slices.Clone(names), "new0")
+	loaded.update(changed)
+	if !slices.Equal(loaded.retired, dealt) {
+		t.Fatalf("retired %v, want the dealt names %v", loaded.retired, dealt)
+	}
+}
+
+func TestNameDeckFindsTheDealtNamesOfOlderVersions(t *testing.T) {
+	names := testNames(20)
+	d := &nameDeck{seed: 3, checksum: nameListChecksum(names)}
+	var dealt []string
+	for range 5 {
+		dealt = append(dealt, d.next(names))
+	}
+
+	old := &nameDeck{seed: 3, position: 5, checksum: nameListChecksum(names)}
+	old.update(names)
+	if !slices.Equal(old.dealt, dealt) {
+		t.Fatalf("the deck found the dealt names %v instead of %v", old.dealt, dealt)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+const (
+	maxNameLength = 20
+	// maxNameWidth limits how wide a name may be when drawn above the gopher's
+	// head. This also keeps the eulogies on the memorial wall on the screen.
+	maxNameWidth = 650
+	// headNameScale is the text scale of the name above the gopher's head.
+	headNameScale = 4
+)
+
+// messageError is an error that is shown to the player in their language.
+type messageError messageID
+
+func (e messageError) Error() string {
+	return tr(messageID(e))
+}
+
+const (
+	errNameTooLong       = messageError(msgNameTooLong)
+	errNameTooWide       = messageError(msgNameTooWide)
+	errNameCannotBeDrawn = messageError(msgNameCannotBeDrawn)
+)
+
+// validateName trims the given name and checks that it fits on the screen. An
+// empty name is valid, it means that random names are used.
+func validateName(window draw.Window, name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if utf8.RuneCountInString(name) > maxNameLength {
+		return name, errNameTooLong
+	}
+	if w, _ := window.GetScaledTextSize(name, headNameScale); w > maxNameWidth {
+		return name, errNameTooWide
+	}
+	if !canDraw(name) {
+		return name, errNameCannotBeDrawn
+	}
+	return name, nil
+}
+
+// nameEntry is a text box that lets the player type the name of their gopher.
+// All keys go into the text box while it is open, flapping is disabled.
+type nameEntry struct {
+	open  bool
+	text  string
+	err   error
+	frame int
+}
+
+func (e *nameEntry) start(name string) {
+	e.open = true
+	e.text = name
+	e.err = nil
+	e.frame = 0
+}
+
+// update handles the typing. It returns true if the player confirmed the name,
+// which is then in e.text.
+func (e *nameEntry) update(window draw.Window) (confirmed bool) {
+	e.frame++
+
+	if window.WasKeyPressed(draw.KeyEscape) {
+		e.open = false
+		return false
+	}
+
+	if window.WasKeyPressed(draw.KeyEnter) || window.WasKeyPressed(draw.KeyNumEnter) {
+		name, err := validateName(window, e.text)
+		e.err = err
+		if err == nil {
+			e.text = name
+			e.open = false
+			return true
+		}
+		return false
+	}
+
+	if window.WasKeyPressed(draw.KeyBackspace) && len(e.text) > 0 {
+		_, size := utf8.DecodeLastRuneInString(e.text)
+		e.text = e.text[:len(e.text)-size]
+	}
+
+	for _, r := range window.Characters() {
+		if unicode.IsPrint(r) {
+			e.text += string(r)
+		}
+	}
+
+	// Tell the player right away if the name does not fit.
+	_, e.err = validateName(window, e.text)
+
+	return false
+}
+
+func (e *nameEntry) draw(window renderer) {
+	windowW, windowH := window.Size()
+
+	const (
+		boxW       = 900
+		boxH       = 260
+		titleScale = 3
+		helpScale  = 1.5
+	)
+	boxX := (windowW - boxW) / 2
+	boxY := (windowH - boxH) / 2
+	window.FillRect(0, 0, windowW, windowH, draw.RGBA(0, 0, 0, 0.4))
+	window.FillRect(boxX, boxY, boxW, boxH, draw.RGBA(1, 1, 1, 0.95))
+
+	title := tr(msgNameYourGopher)
+	titleW, titleH := window.GetScaledTextSize(title, titleScale)
+	window.DrawScaledText(title, boxX+(boxW-titleW)/2, boxY+20, titleScale, draw.RGB(0.5, 0, 0))
+
+	// Draw the name with a blinking cursor behind it.
+	text := e.text
+	if (e.frame/30)%2 == 0 {
+		text += "_"
+	}
+	textW, textH := window.GetScaledTextSize(e.text+"_", headNameScale)
+	textY := boxY + 20 + titleH + 30
+	window.DrawScaledText(text, boxX+(boxW-textW)/2, textY, headNameScale, draw.Black)
+
+	help := tr(msgNameHelp)
+	helpColor := draw.Black
+	if e.err != nil {
+		help = e.err.Error()
+		helpColor = draw.RGB(0.8, 0, 0)
+	}
+	helpW, _ := window.GetScaledTextSize(help, helpScale)
+	helpY := textY + textH + 30
+	window.DrawScaledText(help, boxX+(boxW-helpW)/2, helpY, helpScale, helpColor)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// nameSource provides the names that random gophers are given.
+type nameSource interface {
+	// id identifies the source in the settings and in the kill history. It must
+	// not contain spaces.
+	id() string
+	// title is shown to the player when choosing a name source.
+	title() string
+	names() []string
+}
+
+// customNameSource is the id we record in the kill history for gophers that the
+// player named.
+const customNameSource = "custom"
+
+// defaultNameSource is used if the selected source is not available, e.g. if
+// the user's name file was deleted. Kills from older versions of the game that
+// did not record the name source all came from this source.
+const defaultNameSource = "en"
+
+type bundledNames struct {
+	sourceID    string
+	sourceTitle messageID
+	list        []string
+}
+
+func (b bundledNames) id() string      { return b.sourceID }
+func (b bundledNames) title() string   { return tr(b.sourceTitle) }
+func (b bundledNames) names() []string { return b.list }
+
+var bundledNameSources = []nameSource{
+	bundledNames{"en", msgNamesEnglish, nameList},
+	bundledNames{"de", msgNamesGerman, germanNames},
+	bundledNames{"es", msgNamesSpanish, spanishNames},
+	bundledNames{"ja", msgNamesJapanese, japaneseNames},
+	bundledNames{"gophers", msgNamesGophers, gopherNames},
+	bundledNames{"languages", msgNamesLanguages, languageNames},
+}
+
+// userNames are read from a text file that the player provides, see
+// loadUserNames.
+type userNames struct {
+	list []string
+}
+
+func (userNames) id() string        { return "file" }
+func (userNames) title() string     { return tr(msgNamesFile) }
+func (u userNames) names() []string { return u.list }
+
+// nameSources returns all bundled name sources, the names from the asset pack
+// if it has any and, if the player provided a name file, the names from that
+// file.
+func nameSources() []nameSource {
+	sources := bundledNameSources
+	if currentPack != nil && len(currentPack.names) > 0 {
+		sources = append(sources[:len(sources):len(sources)], packNames{pack: currentPack})
+	}
+	if len(userNameList) > 0 {
+		sources = append(sources[:len(sources):len(sources)], userNames{list: userNameList})
+	}
+	return sources
+}
+
+// userNameList are the names from the player's name file. The settings screen
+// asks for the name sources every frame, so the file is not read every time
+// but in reloadUserNames.
+var userNameList []string
+
+// reloadUserNames reads the player's name file. It is called when the game
+// starts and when the settings open, so a new file can be picked without
+// restarting the game.
+func reloadUserNames() {
+	userNameList = parseNameFile(loadUserNames())
+}
+
+// findNameSource returns the source with the given id or the default source if
+// it is not available.
+func findNameSource(id string) nameSource {
+	var fallback nameSource
+	for _, source := range nameSources() {
+		if source.id() == id {
+			return source
+		}
+		if source.id() == defaultNameSource {
+			fallback = source
+		}
+	}
+	return fallback
+}
+
+// nameSourceTitle is the title of the name source with the given id, as
+// recorded in the kill history. The source might no longer be available.
+func nameSourceTitle(id string) string {
+	switch id {
+	case customNameSource:
+		return tr(msgNamesCustom)
+	case userNames{}.id():
+		return userNames{}.title()
+	}
+	for _, source := range nameSources() {
+		if source.id() == id {
+			return source.title()
+		}
+	}
+	return id
+}
+
+// parseNameFile reads one name per line. Empty lines and lines starting with #
+// are ignored, as are names that are too long or that our font cannot draw.
+// Each name is used only once.
+func parseNameFile(data []byte) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for line := range strings.SplitSeq(string(data), "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" || strings.HasPrefix(name, "#") ||
+			utf8.RuneCountInString(name) > maxNameLength || !canDraw(name) ||
+			seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+	return names
+}
+
+// drawNameSourceLegend lists the name sources of the runs, how many runs had
+// names from each and the best score with them, like drawWeatherLegend. It
+// returns the y below the legend.
+func drawNameSourceLegend(window renderer, kills []kill, x, y, width int) int {
+	const (
+		textScale = 1.2
+		spacing   = 25
+	)
+	var sources []string
+	runs := make(map[string]int)
+	best := make(map[string]int)
+	for _, k := range kills {
+		if runs[k.NameSource] == 0 {
+			sources = append(sources, k.NameSource)
+		}
+		runs[k.NameSource]++
+		best[k.NameSource] = max(best[k.NameSource], k.Score)
+	}
+
+	caption := tr(msgNamesLegend)
+	window.DrawScaledText(caption, x, y, textScale, draw.RGBA(0, 0, 0, 0.9))
+	captionW, lineH := window.GetScaledTextSize(caption, textScale)
+	lineX := x + captionW + spacing
+	for _, source := range sources {
+		text := trn(msgWeatherRuns, runs[source], nameSourceTitle(source), runs[source], best[source])
+		textW, textH := window.GetScaledTextSize(text, textScale)
+		if lineX+textW > x+width {
+			lineX = x + captionW + spacing
+			y += textH + 5
+		}
+		window.DrawScaledText(text, lineX, y, textScale, draw.RGBA(0, 0, 0, 0.9))
+		lineX += textW + spacing
+	}
+	return y + lineH + 5
+}
@@ -0,0 +1,122 @@
+package main
+
+var germanNames = []string{
+	"Adalbert",
+	"Agathe",
+	"Albrecht",
+	"Alma",
+	"Annegret",
+	"Anneliese",
+	"Anton",
+	"Arnold",
+	"August",
+	"Bernhard",
+	"Berta",
+	"Birgit",
+	"Björn",
+	"Brunhilde",
+	"Burkhard",
+	"Bärbel",
+	"Carsten",
+	"Clemens",
+	"Dagmar",
+	"Detlef",
+	"Dieter",
+	"Dietrich",
+	"Dörte",
+	"Eberhard",
+	"Edeltraud",
+	"Egon",
+	"Ekkehard",
+	"Elfriede",
+	"Elke",
+	"Emil",
+	"Erika",
+	"Erna",
+	"Ernst",
+	"Erwin",
+	"Frieda",
+	"Friedhelm",
+	"Fritz",
+	"Gabi",
+	"Gerd",
+	"Gerda",
+	"Gerhard",
+	"Gertrud",
+	"Gisela",
+	"Gottfried",
+	"Gudrun",
+	"Gunther",
+	"Götz",
+	"Günter",
+	"Hannelore",
+	"Hans",
+	"Hansi",
+	"Hartmut",
+	"Hedwig",
+	"Heide",
+	"Heiko",
+	"Heinrich",
+	"Heinz",
+	"Helga",
+	"Helmut",
+	"Henning",
+	"Herbert",
+	"Hermann",
+	"Hilde",
+	"Hildegard",
+	"Horst",
+	"Hubert",
+	"Ilse",
+	"Ingeborg",
+	"Ingrid",
+	"Irmgard",
+	"Jochen",
+	"Jörg",
+	"Jürgen",
+	"Karin",
+	"Karl",
+	"Karla",
+	"Klaus",
+	"Kunigunde",
+	"Lieselotte",
+	"Lothar",
+	"Ludwig",
+	"Lutz",
+	"Magda",
+	"Manfred",
+	"Marlene",
+	"Mechthild",
+	"Notburga",
+	"Olaf",
+	"Ortrud",
+	"Ottilie",
+	"Otto",
+	"Paulchen",
+	"Rainer",
+	"Reinhard",
+	"Reinhold",
+	"Renate",
+	"Roswitha",
+	"Rüdiger",
+	"Sabine",
+	"Siegfried",
+	"Sieglinde",
+	"Sigrid",
+	"Traudl",
+	"Trude",
+	"Udo",
+	"Ulrich",
+	"Ulrike",
+	"Ursula",
+	"Uschi",
+	"Uwe",
+	"Volker",
+	"Walter",
+	"Waltraud",
+	"Werner",
+	"Wiebke",
+	"Wilfried",
+	"Wilhelm",
+	"Wolfgang",
+}
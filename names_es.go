@@ -0,0 +1,105 @@
+package main
+
+var spanishNames = []string{
+	"Adolfo",
+	"Agustín",
+	"Alba",
+	"Alejandro",
+	"Alfonso",
+	"Alicia",
+	"Amparo",
+	"Andrés",
+	"Antonio",
+	"Araceli",
+	"Ares",
+	"Aurora",
+	"Beatriz",
+	"Benito",
+	"Blanca",
+	"Carmen",
+	"Catalina",
+	"Celia",
+	"Concha",
+	"Consuelo",
+	"Cristóbal",
+	"Dolores",
+	"Domingo",
+	"Elena",
+	"Emilio",
+	"Encarna",
+	"Enrique",
+	"Esperanza",
+	"Esteban",
+	"Eulalia",
+	"Federico",
+	"Felipe",
+	"Fermín",
+	"Fernando",
+	"Francisco",
+	"Gonzalo",
+	"Graciela",
+	"Guadalupe",
+	"Gustavo",
+	"Héctor",
+	"Ignacio",
+	"Inés",
+	"Isabel",
+	"Jaime",
+	"Javier",
+	"Jesús",
+	"Jimena",
+	"Joaquín",
+	"Jorge",
+	"Josefa",
+	"José",
+	"Juan",
+	"Juana",
+	"Julián",
+	"Leonor",
+	"Lola",
+	"Lorenzo",
+	"Lucía",
+	"Luis",
+	"Luisa",
+	"Macarena",
+	"Manolo",
+	"Manuel",
+	"Marcelo",
+	"Margarita",
+	"Marisol",
+	"María",
+	"Mercedes",
+	"Miguel",
+	"Montserrat",
+	"Nacho",
+	"Nieves",
+	"Pablo",
+	"Paco",
+	"Paloma",
+	"Pedro",
+	"Pepa",
+	"Pepe",
+	"Pilar",
+	"Purificación",
+	"Rafael",
+	"Ramón",
+	"Raúl",
+	"Remedios",
+	"Ricardo",
+	"Rocío",
+	"Rodrigo",
+	"Rosario",
+	"Salvador",
+	"Santiago",
+	"Sergio",
+	"Socorro",
+	"Soledad",
+	"Teresa",
+	"Tomás",
+	"Valentina",
+	"Vicente",
+	"Xavier",
+	"Yolanda",
+	"Álvaro",
+	"Ángel",
+}
@@ -0,0 +1,62 @@
+package main
+
+var gopherNames = []string{
+	"Bytes",
+	"Channel",
+	"Chomp",
+	"Cookie",
+	"Cowboy",
+	"Defer",
+	"Digby",
+	"Digger",
+	"Dougie",
+	"Dusty",
+	"Gizmo",
+	"Gobbles",
+	"Gofer",
+	"Goldie",
+	"Gomez",
+	"Gonzo",
+	"Gopherbert",
+	"Gopherine",
+	"Gopherson",
+	"Gordon",
+	"Gordy",
+	"Goroutine",
+	"Gozer",
+	"Gus",
+	"Hazel",
+	"Interface",
+	"Lambda",
+	"Mac",
+	"Mole",
+	"Molly",
+	"Mutex",
+	"Nibbles",
+	"Nugget",
+	"Nutmeg",
+	"Panic",
+	"Peanut",
+	"Pebbles",
+	"Pointer",
+	"Pudding",
+	"Recover",
+	"Rune",
+	"Scooter",
+	"Select",
+	"Slice",
+	"Sprocket",
+	"Sputnik",
+	"Squeak",
+	"Struct",
+	"Tater",
+	"Tosh",
+	"Tunnel",
+	"Twiggy",
+	"Unsafe",
+	"Waffles",
+	"WaitGroup",
+	"Whiskers",
+	"Yield",
+	"Ziggy",
+}
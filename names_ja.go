@@ -0,0 +1,105 @@
+package main
+
+var japaneseNames = []string{
+	"Aiko",
+	"Akane",
+	"Akemi",
+	"Akira",
+	"Aoi",
+	"Asuka",
+	"Ayaka",
+	"Ayumi",
+	"Chiharu",
+	"Chika",
+	"Chiyo",
+	"Daichi",
+	"Daiki",
+	"Daisuke",
+	"Emi",
+	"Eri",
+	"Fumiko",
+	"Haruka",
+	"Haruki",
+	"Haruto",
+	"Hayato",
+	"Hikari",
+	"Hina",
+	"Hinata",
+	"Hiroko",
+	"Hiroshi",
+	"Hitomi",
+	"Ichiro",
+	"Isamu",
+	"Jiro",
+	"Jun",
+	"Junko",
+	"Kaede",
+	"Kaito",
+	"Kana",
+	"Kaori",
+	"Kazuki",
+	"Kazuo",
+	"Keiko",
+	"Kenji",
+	"Kenta",
+	"Kiyoshi",
+	"Koharu",
+	"Kota",
+	"Kumiko",
+	"Kyoko",
+	"Mai",
+	"Makoto",
+	"Mami",
+	"Mamoru",
+	"Mao",
+	"Masako",
+	"Masaru",
+	"Mayu",
+	"Megumi",
+	"Michiko",
+	"Midori",
+	"Mika",
+	"Minoru",
+	"Misaki",
+	"Mitsuki",
+	"Miyu",
+	"Momoka",
+	"Naoki",
+	"Naomi",
+	"Natsuki",
+	"Noboru",
+	"Nozomi",
+	"Osamu",
+	"Ren",
+	"Riko",
+	"Rin",
+	"Ryo",
+	"Ryota",
+	"Sakura",
+	"Saori",
+	"Satoshi",
+	"Sayuri",
+	"Shiori",
+	"Shota",
+	"Sora",
+	"Sota",
+	"Suzu",
+	"Tadashi",
+	"Takeshi",
+	"Takumi",
+	"Taro",
+	"Tomoko",
+	"Tsubasa",
+	"Wakana",
+	"Yamato",
+	"Yasuko",
+	"Yoko",
+	"Yoshiko",
+	"Yosuke",
+	"Yui",
+	"Yuka",
+	"Yuki",
+	"Yumiko",
+	"Yuna",
+	"Yuto",
+}
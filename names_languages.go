@@ -0,0 +1,87 @@
+package main
+
+var languageNames = []string{
+	"ABAP",
+	"Ada",
+	"ALGOL",
+	"APL",
+	"AppleScript",
+	"Assembly",
+	"AWK",
+	"Bash",
+	"BASIC",
+	"BCPL",
+	"C",
+	"C#",
+	"C++",
+	"Clojure",
+	"COBOL",
+	"CoffeeScript",
+	"Crystal",
+	"D",
+	"Dart",
+	"Delphi",
+	"Eiffel",
+	"Elixir",
+	"Elm",
+	"Erlang",
+	"F#",
+	"Forth",
+	"Fortran",
+	"Gleam",
+	"Go",
+	"Groovy",
+	"Haskell",
+	"Haxe",
+	"Idris",
+	"Io",
+	"J",
+	"Java",
+	"JavaScript",
+	"Julia",
+	"Kotlin",
+	"Lisp",
+	"Logo",
+	"Lua",
+	"MATLAB",
+	"Miranda",
+	"ML",
+	"Modula-2",
+	"Nim",
+	"Oberon",
+	"Objective-C",
+	"OCaml",
+	"Odin",
+	"Pascal",
+	"Perl",
+	"PHP",
+	"Pike",
+	"PL/I",
+	"PostScript",
+	"Prolog",
+	"PureScript",
+	"Python",
+	"R",
+	"Racket",
+	"Raku",
+	"REXX",
+	"Ruby",
+	"Rust",
+	"SAS",
+	"Scala",
+	"Scheme",
+	"Scratch",
+	"Self",
+	"Simula",
+	"Smalltalk",
+	"Snobol",
+	"SQL",
+	"Swift",
+	"Tcl",
+	"TypeScript",
+	"V",
+	"Vala",
+	"Verilog",
+	"VHDL",
+	"Zig",
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// networkInputs are the values from an observation that a network sees,
+	// see networkInput.
+	networkInputs = 2 + 2*envGapCount
+	networkHidden = 8
+)
+
+// network decides when a gopher flaps. It has one hidden layer and a single
+// output, the gopher flaps when the output is positive. The weights are
+// trained with neuroevolution, see trainer.
+type network struct {
+	inputs, hidden int
+	// weights has a row for every hidden neuron, a bias followed by a weight
+	// for every input, then the output's row, a bias followed by a weight for
+	// every hidden neuron.
+	weights []float64
+}
+
+func newRandomNetwork(r *rand.Rand) *network {
+	n := &network{inputs: networkInputs, hidden: networkHidden}
+	n.weights = make([]float64, n.hidden*(n.inputs+1)+n.hidden+1)
+	for i := range n.weights {
+		n.weights[i] = r.NormFloat64()
+	}
+	return n
+}
+
+// networkInput scales the observation to values around -1 to 1, relative to
+// the gopher. Missing gaps are all zeros.
+func networkInput(obs observation) []float64 {
+	in := []float64{
+		(obs.Y - windowH/2) / windowH,
+		obs.YSpeed / -clickYSpeed,
+	}
+	for i := range envGapCount {
+		if i < len(obs.Gaps) {
+			gap := obs.Gaps[i]
+			in = append(in, gap.DX/gapDistX, (float64(gap.CenterY)-obs.Y)/windowH)
+		} else {
+			in = append(in, 0, 0)
+		}
+	}
+	return in
+}
+
+func (n *network) flap(obs observation) bool {
+	in := networkInput(obs)
+	w := n.weights
+	out := w[n.hidden*(n.inputs+1)]
+	for h := range n.hidden {
+		row := w[h*(n.inputs+1) : (h+1)*(n.inputs+1)]
+		sum := row[0]
+		for i, x := range in {
+			sum += row[i+1] * x
+		}
+		out += w[n.hidden*(n.inputs+1)+1+h] * math.Tanh(sum)
+	}
+	return out > 0
+}
+
+// mutate adds normally distributed noise of the given strength to every
+// weight with the given probability.
+func (n *network) mutate(r *rand.Rand, probability, strength float64) {
+	for i := range n.weights {
+		if r.Float64() < probability {
+			n.weights[i] += r.NormFloat64() * strength
+		}
+	}
+}
+
+// crossover makes a child which has every weight from one of the parents,
+// picked at random.
+func crossover(r *rand.Rand, a, b *network) *network {
+	child := &network{inputs: a.inputs, hidden: a.hidden, weights: slices.Clone(a.weights)}
+	for i := range child.weights {
+		if r.Intn(2) == 0 {
+			child.weights[i] = b.weights[i]
+		}
+	}
+	return child
+}
+
+// networkToBytes writes the network as text. The first line is "network",
+// the number of inputs and the number of hidden neurons. Then comes a line for
+// every hidden neuron and one for the output with the weights of the rows as
+// described in network.
+func networkToBytes(n *network) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "network %d %d\n", n.inputs, n.hidden)
+	writeRow := func(weights []float64) {
+		for i, w := range weights {
+			if i > 0 {
+				buf.WriteString(" ")
+			}
+			buf.WriteString(strconv.FormatFloat(w, 'g', -1, 64))
+		}
+		buf.WriteString("\n")
+	}
+	rowLen := n.inputs + 1
+	for h := range n.hidden {
+		writeRow(n.weights[h*rowLen : (h+1)*rowLen])
+	}
+	writeRow(n.weights[n.hidden*rowLen:])
+	return buf.Bytes()
+}
+
+func bytesToNetwork(data []byte) (*network, error) {
+	fields := strings.Fields(string(data))
+	if len(fields) < 3 || fields[0] != "network" {
+		return nil, fmt.Errorf("not a network file")
+	}
+	n := &network{}
+	var err error
+	if n.inputs, err = strconv.Atoi(fields[1]); err != nil {
+		return nil, fmt.Errorf("invalid network inputs: %w", err)
+	}
+	if n.hidden, err = strconv.Atoi(fields[2]); err != nil {
+		return nil, fmt.Errorf("invalid network hidden size: %w", err)
+	}
+	if n.inputs != networkInputs {
+		return nil, fmt.Errorf("the network has %d inputs, it must have %d", n.inputs, networkInputs)
+	}
+	want := n.hidden*(n.inputs+1) + n.hidden + 1
+	if n.hidden <= 0 || len(fields)-3 != want {
+		return nil, fmt.Errorf("the network has %d weights, it must have %d", len(fields)-3, want)
+	}
+	for _, field := range fields[3:] {
+		w, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network weight: %w", err)
+		}
+		n.weights = append(n.weights, w)
+	}
+	return n, nil
+}
+
+func loadNetwork(path string) (*network, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	n, err := bytesToNetwork(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return n, nil
+}
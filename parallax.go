@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"slices"
+)
+
+// parallaxLayer is a row of images that scroll by slower than the pipes, which
+// makes them look farther away. New images are added on the right as the old
+// ones leave the window on the left. The layers are described in
+// rsc/parallax.json, see parseParallaxLayers.
+type parallaxLayer struct {
+	// Name is only used in error messages.
+	Name string `json:"name"`
+	// Images are picked at random for new items. If the current biome has
+	// its own images in BiomeImages, those are used instead.
+	Images      []string            `json:"images"`
+	BiomeImages map[string][]string `json:"biomeImages"`
+	// Speed is relative to the gopher's speed. Layers with a speed above 1
+	// look nearer than the pipes.
+	Speed float64 `json:"speed"`
+	// Drift moves the items to the left by this many pixels per frame on top
+	// of Speed, even if the gopher stops, e.g. for birds.
+	Drift float64 `json:"drift"`
+	// Every item gets a random scale between MinScale and MaxScale. If
+	// ScaleSpeed is set, larger items are considered nearer and move faster.
+	MinScale   float64 `json:"minScale"`
+	MaxScale   float64 `json:"maxScale"`
+	ScaleSpeed bool    `json:"scaleSpeed"`
+	// Items are placed at a random y between MinY and MaxY. If Bottom is set,
+	// y is measured from the bottom of the window to the bottom of the image.
+	MinY   int  `json:"minY"`
+	MaxY   int  `json:"maxY"`
+	Bottom bool `json:"bottom"`
+	// Spacing is the horizontal distance between two items, a random value of
+	// up to RandomSpacing is added to it. Negative spacings make the items
+	// overlap.
+	Spacing       int `json:"spacing"`
+	RandomSpacing int `json:"randomSpacing"`
+	// Foreground layers are drawn in front of the pipes. All other layers are
+	// behind the pipes and get darker at night.
+	Foreground bool `json:"foreground"`
+	// Weather marks the place between the other layers where rain and snow
+	// are drawn. A weather layer has no images of its own.
+	Weather bool `json:"weather"`
+
+	items []parallaxItem
+	// nextSpacing is the distance between the last item and the next one.
+	nextSpacing float64
+	// rand places the items, it is seeded in reset.
+	rand *rand.Rand
+}
+
+// imageSizer knows the size of the window and of the images. Every renderer is
+// one, so is the game, which has no window.
+type imageSizer interface {
+	Size() (width, height int)
+	ImageSize(path string) (width, height int, err error)
+}
+
+type parallaxItem struct {
+	image string
+	x     float64
+	y     int
+	scale float64
+}
+
+// parseParallaxLayers reads the layers from a JSON file of the form
+//
+//	{"layers": [{"name": "clouds", "images": ["rsc/cloud.png"], ...}, ...]}
+//
+// The layers are drawn in the given order. A layer without scales has scale 1.
+func parseParallaxLayers(data []byte) ([]*parallaxLayer, error) {
+	var file struct {
+		Layers []*parallaxLayer `json:"layers"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+
+	for _, l := range file.Layers {
+		if l.Weather {
+			continue
+		}
+		if len(l.Images) == 0 {
+			return nil, fmt.Errorf("parallax layer %q has no images", l.Name)
+		}
+		for biome, images := range l.BiomeImages {
+			if len(images) == 0 {
+				return nil, fmt.Errorf("parallax layer %q has no images for biome %q", l.Name, biome)
+			}
+		}
+		if l.MinScale == 0 && l.MaxScale == 0 {
+			l.MinScale, l.MaxScale = 1, 1
+		}
+		if l.MinScale <= 0 || l.MaxScale < l.MinScale {
+			return nil, fmt.Errorf("parallax layer %q has invalid scales", l.Name)
+		}
+		if l.MaxY < l.MinY {
+			return nil, fmt.Errorf("parallax layer %q has maxY below minY", l.Name)
+		}
+		if l.RandomSpacing < 0 {
+			return nil, fmt.Errorf("parallax layer %q has a negative random spacing", l.Name)
+		}
+		if l.Speed < 0 || l.Drift < 0 || l.Speed == 0 && l.Drift == 0 {
+			return nil, fmt.Errorf("parallax layer %q must move to the left", l.Name)
+		}
+	}
+
+	return file.Layers, nil
+}
+
+// loadParallaxLayers reads rsc/parallax.json which is embedded in the
+// executable and must be valid. Asset packs can replace it, they are validated
+// when they are loaded.
+func loadParallaxLayers() []*parallaxLayer {
+	data, err := fs.ReadFile(assets, "rsc/parallax.json")
+	if err != nil {
+		panic(err)
+	}
+	layers, err := parseParallaxLayers(data)
+	if err != nil {
+		panic(err)
+	}
+	return layers
+}
+
+// allImages returns every image that the layer can show.
+func (l *parallaxLayer) allImages() []string {
+	images := slices.Clone(l.Images)
+	for _, list := range l.BiomeImages {
+		images = append(images, list...)
+	}
+	return images
+}
+
+// reset removes all items. The items of the next run are placed at random, the
+// same for the same seed.
+func (l *parallaxLayer) reset(seed int64) {
+	l.items = l.items[:0]
+	l.rand = rand.New(rand.NewSource(seed))
+}
+
+// update moves the items to the left by the layer's fraction of xSpeed.
+// Items that leave the window are removed and new items for the given biome
+// are added on the right.
+func (l *parallaxLayer) update(window imageSizer, xSpeed float64, biome string) {
+	if l.Weather {
+		return
+	}
+
+	kept := l.items[:0]
+	for _, item := range l.items {
+		item.x -= xSpeed*l.itemSpeed(item) + l.Drift
+		if item.x+l.width(window, item) > 0 {
+			kept = append(kept, item)
+		}
+	}
+	l.items = kept
+
+	// New items are added once there is room for them on the right, so they
+	// move into the window seamlessly.
+	windowW, _ := window.Size()
+	l.fill(window, float64(windowW), biome)
+}
+
+// fill adds items on the right until they reach the given x.
+func (l *parallaxLayer) fill(window imageSizer, right float64, biome string) {
+	nextX := -float64(l.rand.Intn(l.RandomSpacing + 1))
+	if len(l.items) > 0 {
+		last := l.items[len(l.items)-1]
+		nextX = last.x + l.width(window, last) + l.nextSpacing
+	}
+	for nextX < right {
+		item := l.newItem(window, biome, nextX)
+		l.items = append(l.items, item)
+		l.nextSpacing = float64(l.Spacing + l.rand.Intn(l.RandomSpacing+1))
+		// Always move on, even for images that are too small for the
+		// spacing or that do not load.
+		nextX += max(1, l.width(window, item)+l.nextSpacing)
+	}
+}
+
+// extended returns a copy of the layer with enough items on the right to move
+// the camera panX pixels to the right, for the photo mode. The copy is only
+// drawn, never updated, and it places its new items with r.
+func (l *parallaxLayer) extended(window imageSizer, panX float64, biome string, r *rand.Rand) *parallaxLayer {
+	c := *l
+	c.items = slices.Clone(l.items)
+	c.rand = r
+	if c.Weather {
+		return &c
+	}
+	fastest := l.Speed
+	if l.ScaleSpeed {
+		fastest *= l.MaxScale
+	}
+	windowW, _ := window.Size()
+	c.fill(window, float64(windowW)+panX*fastest, biome)
+	return &c
+}
+
+// itemSpeed is how fast the item moves relative to the gopher.
+func (l *parallaxLayer) itemSpeed(item parallaxItem) float64 {
+	if l.ScaleSpeed {
+		return l.Speed * item.scale
+	}
+	return l.Speed
+}
+
+func (l *parallaxLayer) newItem(window imageSizer, biome string, x float64) parallaxItem {
+	images := l.Images
+	if list, ok := l.BiomeImages[biome]; ok {
+		images = list
+	}
+	item := parallaxItem{
+		image: images[l.rand.Intn(len(images))],
+		x:     x,
+		y:     l.MinY + l.rand.Intn(l.MaxY-l.MinY+1),
+		scale: l.MinScale + l.rand.Float64()*(l.MaxScale-l.MinScale),
+	}
+	if l.Bottom {
+		_, windowH := window.Size()
+		item.y = windowH - l.height(window, item) - item.y
+	}
+	return item
+}
+
+func (l *parallaxLayer) width(window imageSizer, item parallaxItem) float64 {
+	w, _, _ := window.ImageSize(item.image)
+	return float64(w) * item.scale
+}
+
+func (l *parallaxLayer) height(window imageSizer, item parallaxItem) int {
+	_, h, _ := window.ImageSize(item.image)
+	return round(float64(h) * item.scale)
+}
+
+// draw draws the items with the camera moved panX pixels to the right of the
+// gopher, which moves far items less than near ones.
+func (l *parallaxLayer) draw(window renderer, panX float64) {
+	for _, item := range l.items {
+		w, h, _ := window.ImageSize(item.image)
+		w = round(float64(w) * item.scale)
+		h = round(float64(h) * item.scale)
+		x := round(item.x - panX*l.itemSpeed(item))
+		window.DrawImageFileTo(item.image, x, item.y, w, h, 0)
+	}
+}
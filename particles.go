@@ -0,0 +1,225 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// maxParticles is the size of the particle pool. If more particles are alive
+// at once, new ones replace the oldest.
+const maxParticles = 512
+
+// particleShape says how a particle is drawn.
+type particleShape int
+
+const (
+	// particleDot is a filled circle, e.g. dust.
+	particleDot particleShape = iota
+	// particleStreak is a line along the particle's rotation, e.g. a feather.
+	particleStreak
+	// particleChunk is a filled square, e.g. a piece of a pipe.
+	particleChunk
+	// particleStar is two crossed lines.
+	particleStar
+)
+
+// emitter describes a burst of particles. Every particle gets random values in
+// the given ranges. Angles are in degrees, 0 is to the right and 90 is down.
+type emitter struct {
+	shape              particleShape
+	colors             []draw.Color
+	minCount, maxCount int
+	minSpeed, maxSpeed float64
+	angle, spread      float64
+	minSize, maxSize   int
+	minLife, maxLife   int
+	gravity            float64
+	// drag is the fraction of the speed that is kept from frame to frame.
+	drag float64
+	// spin is the maximum rotation in degrees per frame, in either direction.
+	spin float64
+	// fade makes the particles more transparent towards the end of their life.
+	fade bool
+}
+
+var (
+	// flapEmitter blows some dust and a feather out behind the gopher.
+	flapEmitter = emitter{
+		shape:    particleDot,
+		colors:   []draw.Color{rgba(230, 220, 200, 180), rgba(200, 190, 170, 160)},
+		minCount: 4, maxCount: 7,
+		minSpeed: 1, maxSpeed: 4,
+		angle: 150, spread: 60,
+		minSize: 4, maxSize: 9,
+		minLife: 15, maxLife: 30,
+		gravity: -0.05,
+		drag:    0.9,
+		fade:    true,
+	}
+	featherEmitter = emitter{
+		shape:    particleStreak,
+		colors:   []draw.Color{rgb(89, 218, 253), rgb(220, 245, 255)},
+		minCount: 0, maxCount: 2,
+		minSpeed: 2, maxSpeed: 5,
+		angle: 120, spread: 50,
+		minSize: 8, maxSize: 14,
+		minLife: 30, maxLife: 50,
+		gravity: 0.1,
+		drag:    0.92,
+		spin:    10,
+		fade:    true,
+	}
+	// scoreEmitter sparkles all around the gopher for every cleared pipe.
+	scoreEmitter = emitter{
+		shape:    particleDot,
+		colors:   []draw.Color{rgb(255, 220, 60), rgb(255, 250, 200), rgb(255, 170, 30)},
+		minCount: 12, maxCount: 16,
+		minSpeed: 3, maxSpeed: 7,
+		angle: 270, spread: 360,
+		minSize: 3, maxSize: 6,
+		minLife: 20, maxLife: 35,
+		gravity: 0.05,
+		drag:    0.9,
+		fade:    true,
+	}
+	// pipeEmitter breaks pieces off a pipe that the gopher hits. Their colors
+	// are multiplied by the pipe's tint.
+	pipeEmitter = emitter{
+		shape:    particleChunk,
+		colors:   []draw.Color{rgb(87, 171, 42), rgb(60, 120, 30), rgb(130, 200, 80)},
+		minCount: 10, maxCount: 16,
+		minSpeed: 3, maxSpeed: 9,
+		angle: 180, spread: 140,
+		minSize: 4, maxSize: 10,
+		minLife: 40, maxLife: 70,
+		gravity: 0.5,
+		drag:    0.98,
+		spin:    15,
+	}
+	// ceilingEmitter makes the gopher see stars when it bumps its head.
+	ceilingEmitter = emitter{
+		shape:    particleStar,
+		colors:   []draw.Color{rgb(255, 230, 0), rgb(255, 255, 255)},
+		minCount: 5, maxCount: 8,
+		minSpeed: 2, maxSpeed: 5,
+		angle: 90, spread: 160,
+		minSize: 10, maxSize: 18,
+		minLife: 40, maxLife: 60,
+		gravity: 0.1,
+		drag:    0.93,
+		spin:    8,
+		fade:    true,
+	}
+)
+
+type particle struct {
+	shape          particleShape
+	color          draw.Color
+	x, y           float64
+	xSpeed, ySpeed float64
+	rotation, spin float64
+	size           int
+	life, maxLife  int
+	gravity, drag  float64
+	fade           bool
+}
+
+// particleSystem owns a fixed pool of particles. They live in world
+// coordinates, so they stay where they were emitted while the gopher flies on.
+// The system has its own random numbers so that the particles of a run are the
+// same for the same seed.
+type particleSystem struct {
+	particles [maxParticles]particle
+	// next is the pool index that the next particle is put into.
+	next int
+	rand *rand.Rand
+}
+
+// reset removes all particles and starts a new run with the given seed.
+func (s *particleSystem) reset(seed int64) {
+	s.particles = [maxParticles]particle{}
+	s.next = 0
+	s.rand = rand.New(rand.NewSource(seed))
+}
+
+// emit adds a burst of particles at the given world position.
+func (s *particleSystem) emit(e emitter, x, y float64) {
+	s.emitTinted(e, x, y, draw.White)
+}
+
+// emitTinted is like emit with all colors multiplied by the tint.
+func (s *particleSystem) emitTinted(e emitter, x, y float64, tint draw.Color) {
+	r := s.rand
+	count := e.minCount + r.Intn(e.maxCount-e.minCount+1)
+	for range count {
+		angle := (e.angle + (r.Float64()-0.5)*e.spread) * math.Pi / 180
+		speed := e.minSpeed + r.Float64()*(e.maxSpeed-e.minSpeed)
+		life := e.minLife + r.Intn(e.maxLife-e.minLife+1)
+		color := e.colors[r.Intn(len(e.colors))]
+		color.R *= tint.R
+		color.G *= tint.G
+		color.B *= tint.B
+		s.particles[s.next] = particle{
+			shape:    e.shape,
+			color:    color,
+			x:        x,
+			y:        y,
+			xSpeed:   math.Cos(angle) * speed,
+			ySpeed:   math.Sin(angle) * speed,
+			rotation: r.Float64() * 360,
+			spin:     (r.Float64()*2 - 1) * e.spin,
+			size:     e.minSize + r.Intn(e.maxSize-e.minSize+1),
+			life:     life,
+			maxLife:  life,
+			gravity:  e.gravity,
+			drag:     e.drag,
+			fade:     e.fade,
+		}
+		s.next = (s.next + 1) % maxParticles
+	}
+}
+
+// update moves all particles by one frame.
+func (s *particleSystem) update() {
+	for i := range s.particles {
+		p := &s.particles[i]
+		if p.life <= 0 {
+			continue
+		}
+		p.life--
+		p.x += p.xSpeed
+		p.y += p.ySpeed
+		p.xSpeed *= p.drag
+		p.ySpeed = p.ySpeed*p.drag + p.gravity
+		p.rotation += p.spin
+	}
+}
+
+// draw draws the particles, cameraX is the world x at the window's left.
+func (s *particleSystem) draw(window renderer, cameraX float64) {
+	for _, p := range s.particles {
+		if p.life <= 0 {
+			continue
+		}
+		color := p.color
+		if p.fade {
+			color.A *= float32(p.life) / float32(p.maxLife)
+		}
+		x, y := round(p.x-cameraX), round(p.y)
+		half := p.size / 2
+		switch p.shape {
+		case particleDot:
+			window.FillEllipse(x-half, y-half, p.size, p.size, color)
+		case particleChunk:
+			window.FillRect(x-half, y-half, p.size, p.size, color)
+		case particleStreak, particleStar:
+			dx, dy := rotateOffset(half, 0, round(p.rotation))
+			window.DrawLine(x-dx, y-dy, x+dx, y+dy, color)
+			if p.shape == particleStar {
+				window.DrawLine(x+dy, y-dx, x-dy, y+dx, color)
+			}
+		}
+	}
+}
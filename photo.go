@@ -0,0 +1,87 @@
+package main
+
+import (
+	"math/rand"
+	"strconv"
+	"unicode"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// photoMode freezes the game and hides all texts, so the player can take nice
+// screenshots. The camera can be moved to the right to look at the pipes that
+// are coming up, and back.
+type photoMode struct {
+	open bool
+	// panX is how far the camera is moved to the right of the gopher.
+	panX float64
+	// layers are copies of the game's parallax layers with enough items for
+	// the whole way, see parallaxLayer.extended.
+	layers []*parallaxLayer
+}
+
+const (
+	// photoMaxPan is how far the camera can move. The game only knows the
+	// next few pipes, so it cannot go much farther.
+	photoMaxPan     = 2 * windowW
+	photoPanSpeed   = 20
+	photoWheelSpeed = 80
+)
+
+func (p *photoMode) start(g *game) {
+	p.open = true
+	p.panX = 0
+	biome := biomeAt(pipeAt(g.x + windowW)).name
+	r := rand.New(rand.NewSource(rand.Int63()))
+	p.layers = p.layers[:0]
+	for _, layer := range g.parallax {
+		p.layers = append(p.layers, layer.extended(g, photoMaxPan, biome, r))
+	}
+}
+
+// update moves the camera with the arrow keys and the mouse wheel.
+func (p *photoMode) update(window draw.Window) {
+	if window.IsKeyDown(draw.KeyLeft) {
+		p.panX -= photoPanSpeed
+	}
+	if window.IsKeyDown(draw.KeyRight) {
+		p.panX += photoPanSpeed
+	}
+	p.panX += (window.MouseWheelX() - window.MouseWheelY()) * photoWheelSpeed
+	p.panX = min(photoMaxPan, max(0, p.panX))
+}
+
+// drawHelp explains the photo mode at the top of the window. It is not part of
+// the screenshots.
+func (p *photoMode) drawHelp(window renderer, c *controls) {
+	const helpScale = 2
+	help := tr(msgPhotoModeHelp, c.describe(actionScreenshot), c.describe(actionPhoto))
+	helpW, helpH := window.GetScaledTextSize(help, helpScale)
+	windowW, _ := window.Size()
+	window.FillRect(0, 0, windowW, helpH+20, draw.RGBA(0, 0, 0, 0.4))
+	window.DrawScaledText(help, (windowW-helpW)/2, 10, helpScale, draw.White)
+}
+
+// fileName makes a file name from the gopher's name and score, without the
+// characters that are not allowed in file names.
+func fileName(name string, score int) string {
+	var safe []rune
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			safe = append(safe, r)
+		} else {
+			safe = append(safe, '_')
+		}
+	}
+	return string(safe) + "_" + strconv.Itoa(score)
+}
+
+// drawNotice shows a short message at the bottom of the window.
+func drawNotice(window renderer, text string) {
+	const noticeScale = 2
+	windowW, windowH := window.Size()
+	textW, textH := window.GetScaledTextSize(text, noticeScale)
+	x, y := (windowW-textW)/2, windowH-textH-60
+	window.FillRect(x-10, y-5, textW+20, textH+10, draw.RGBA(0, 0, 0, 0.6))
+	window.DrawScaledText(text, x, y, noticeScale, draw.White)
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"github.com/gonutz/prototype/draw"
+)
+
+// scaledWindow lets the game draw into a virtual screen of a fixed size, no
+// matter how large the real window is. Everything is scaled to fit the real
+// window, keeping the aspect ratio, and centered in it. The borders that are
+// left over are filled black after each frame, see drawBorders.
+//
+// Image and text sizes are in virtual pixels, which are the same as the
+// unscaled sizes.
+type scaledWindow struct {
+	draw.Window
+	width, height    int
+	scale            float64
+	offsetX, offsetY int
+	// blockKeys hides all key presses from the game for one frame, e.g. if the
+	// keys were already used to toggle fullscreen.
+	blockKeys bool
+}
+
+func newScaledWindow(width, height int) *scaledWindow {
+	return &scaledWindow{width: width, height: height, scale: 1}
+}
+
+// fit sets the real window for the next frame and scales the virtual screen to
+// it.
+func (w *scaledWindow) fit(window draw.Window) {
+	w.Window = window
+	realW, realH := window.Size()
+	w.scale = min(float64(realW)/float64(w.width), float64(realH)/float64(w.height))
+	if w.scale <= 0 {
+		w.scale = 1
+	}
+	w.offsetX = (realW - round(float64(w.width)*w.scale)) / 2
+	w.offsetY = (realH - round(float64(w.height)*w.scale)) / 2
+}
+
+// drawBorders hides everything that was drawn outside the virtual screen.
+func (w *scaledWindow) drawBorders() {
+	realW, realH := w.Window.Size()
+	left, top := w.toReal(0, 0)
+	right, bottom := w.toReal(w.width, w.height)
+	w.Window.FillRect(0, 0, realW, top, draw.Black)
+	w.Window.FillRect(0, bottom, realW, realH-bottom, draw.Black)
+	w.Window.FillRect(0, top, left, bottom-top, draw.Black)
+	w.Window.FillRect(right, top, realW-right, bottom-top, draw.Black)
+}
+
+func (w *scaledWindow) toReal(x, y int) (int, int) {
+	return w.offsetX + round(float64(x)*w.scale), w.offsetY + round(float64(y)*w.scale)
+}
+
+func (w *scaledWindow) toVirtual(x, y int) (int, int) {
+	return round(float64(x-w.offsetX) / w.scale), round(float64(y-w.offsetY) / w.scale)
+}
+
+// toRealRect scales both corners of the rectangle, so that rectangles which
+// touch in virtual pixels also touch in real pixels.
+func (w *scaledWindow) toRealRect(x, y, width, height int) (int, int, int, int) {
+	left, top := w.toReal(x, y)
+	right, bottom := w.toReal(x+width, y+height)
+	return left, top, right - left, bottom - top
+}
+
+// lineWidth is how thick lines have to be to look as thick as one virtual
+// pixel.
+func (w *scaledWindow) lineWidth() int {
+	return max(1, round(w.scale))
+}
+
+func (w *scaledWindow) Size() (int, int) {
+	return w.width, w.height
+}
+
+func (w *scaledWindow) WasKeyPressed(key draw.Key) bool {
+	return !w.blockKeys && w.Window.WasKeyPressed(key)
+}
+
+func (w *scaledWindow) MousePosition() (int, int) {
+	return w.toVirtual(w.Window.MousePosition())
+}
+
+func (w *scaledWindow) Clicks() []draw.MouseClick {
+	clicks := w.Window.Clicks()
+	scaled := make([]draw.MouseClick, len(clicks))
+	for i, c := range clicks {
+		c.X, c.Y = w.toVirtual(c.X, c.Y)
+		scaled[i] = c
+	}
+	return scaled
+}
+
+func (w *scaledWindow) DrawPoint(x, y int, color draw.Color) {
+	w.FillRect(x, y, 1, 1, color)
+}
+
+func (w *scaledWindow) DrawLine(fromX, fromY, toX, toY int, color draw.Color) {
+	fromX, fromY = w.toReal(fromX, fromY)
+	toX, toY = w.toReal(toX, toY)
+	// Thick lines are made of parallel lines, moved along the shorter axis.
+	dx, dy := 0, 1
+	if abs(toY-fromY) > abs(toX-fromX) {
+		dx, dy = 1, 0
+	}
+	for i := range w.lineWidth() {
+		w.Window.DrawLine(fromX+i*dx, fromY+i*dy, toX+i*dx, toY+i*dy, color)
+	}
+}
+
+func (w *scaledWindow) DrawRect(x, y, width, height int, color draw.Color) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	x, y, width, height = w.toRealRect(x, y, width, height)
+	t := min(w.lineWidth(), width, height)
+	w.Window.FillRect(x, y, width, t, color)
+	w.Window.FillRect(x, y+height-t, width, t, color)
+	w.Window.FillRect(x, y+t, t, height-2*t, color)
+	w.Window.FillRect(x+width-t, y+t, t, height-2*t, color)
+}
+
+func (w *scaledWindow) FillRect(x, y, width, height int, color draw.Color) {
+	x, y, width, height = w.toRealRect(x, y, width, height)
+	w.Window.FillRect(x, y, width, height, color)
+}
+
+func (w *scaledWindow) DrawEllipse(x, y, width, height int, color draw.Color) {
+	x, y, width, height = w.toRealRect(x, y, width, height)
+	for i := range w.lineWidth() {
+		w.Window.DrawEllipse(x+i, y+i, width-2*i, height-2*i, color)
+	}
+}
+
+func (w *scaledWindow) FillEllipse(x, y, width, height int, color draw.Color) {
+	x, y, width, height = w.toRealRect(x, y, width, height)
+	w.Window.FillEllipse(x, y, width, height, color)
+}
+
+func (w *scaledWindow) DrawImageFile(path string, x, y int) error {
+	return w.DrawImageFileRotated(path, x, y, 0)
+}
+
+func (w *scaledWindow) DrawImageFileTo(path string, x, y, width, height, rotationCWDeg int) error {
+	x, y, width, height = w.toRealRect(x, y, width, height)
+	return w.Window.DrawImageFileTo(path, x, y, width, height, rotationCWDeg)
+}
+
+func (w *scaledWindow) DrawImageFileRotated(path string, x, y, rotationCWDeg int) error {
+	width, height, err := w.Window.ImageSize(path)
+	if err != nil {
+		return err
+	}
+	return w.DrawImageFileTo(path, x, y, width, height, rotationCWDeg)
+}
+
+func (w *scaledWindow) DrawImageFilePart(
+	path string,
+	sourceX, sourceY, sourceWidth, sourceHeight int,
+	destX, destY, destWidth, destHeight int,
+	rotationCWDeg int,
+) error {
+	destX, destY, destWidth, destHeight = w.toRealRect(destX, destY, destWidth, destHeight)
+	return w.Window.DrawImageFilePart(
+		path,
+		sourceX, sourceY, sourceWidth, sourceHeight,
+		destX, destY, destWidth, destHeight,
+		rotationCWDeg,
+	)
+}
+
+func (w *scaledWindow) DrawText(text string, x, y int, color draw.Color) {
+	w.DrawScaledText(text, x, y, 1, color)
+}
+
+func (w *scaledWindow) DrawScaledText(text string, x, y int, scale float32, color draw.Color) {
+	x, y = w.toReal(x, y)
+	w.Window.DrawScaledText(text, x, y, scale*float32(w.scale), color)
+}
@@ -0,0 +1,35 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// saveScreenshot writes the image as a PNG file next to the kill history. The
+// name has no extension, a number is added to it if the file exists already.
+// It returns the file's path.
+func saveScreenshot(img image.Image, name string) (string, error) {
+	path := filepath.Join(historyDir(), name+".png")
+	for i := 2; ; i++ {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0666)
+		if errors.Is(err, fs.ErrExist) {
+			path = filepath.Join(historyDir(), fmt.Sprintf("%s_%d.png", name, i))
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if err := png.Encode(f, img); err != nil {
+			f.Close()
+			return "", err
+		}
+		return path, f.Close()
+	}
+}
@@ -0,0 +1,25 @@
+//go:build js
+
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	"image/png"
+	"syscall/js"
+)
+
+// saveScreenshot lets the browser download the image as a PNG file. The name
+// has no extension. It returns the file's name.
+func saveScreenshot(img image.Image, name string) (string, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return "", err
+	}
+	link := js.Global().Get("document").Call("createElement", "a")
+	link.Set("href", "data:image/png;base64,"+base64.StdEncoding.EncodeToString(buf.Bytes()))
+	link.Set("download", name+".png")
+	link.Call("click")
+	return name + ".png", nil
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bytes"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// settings are the player's preferences which are kept between runs of the
+// program. They are stored as lines of text, each line starts with a key and
+// is followed by the values for that key, separated by spaces. Unknown keys are
+// ignored so older versions of the game can read newer settings files.
+type settings struct {
+	controls controls
+	// customName is the name the player gave their gopher. If it is empty,
+	// every gopher gets a random name.
+	customName string
+	// nameSource is the id of the nameSource that random names are taken
+	// from.
+	nameSource string
+	// nameDecks are the decks for each name source. If there is no deck for a
+	// source yet, we create one on first use.
+	nameDecks map[string]*nameDeck
+	// language is the id of the language that all texts are shown in.
+	language string
+	// wardrobe maps accessory group names to what the player chose for that
+	// group: the name of an accessory or wearNothing. Groups that are not in
+	// the wardrobe are random.
+	wardrobe map[string]string
+	// pack is the path of the asset pack to use, empty for none. It is used
+	// the next time that the game starts.
+	pack string
+	// fullscreen is true if the game was in fullscreen mode when it was last
+	// toggled. The game starts in the same mode next time.
+	fullscreen bool
+	// accessoryCollision makes the gopher's accessories hit the pipes, not
+	// only the gopher itself.
+	accessoryCollision bool
+}
+
+func defaultSettings() settings {
+	return settings{
+		controls:   defaultControls(),
+		nameSource: defaultNameSource,
+		nameDecks:  make(map[string]*nameDeck),
+		language:   english.id,
+		wardrobe:   make(map[string]string),
+	}
+}
+
+func settingsToBytes(s settings) []byte {
+	var buf bytes.Buffer
+	for a := range actionCount {
+		buf.WriteString("bind ")
+		buf.WriteString(actionIDs[a])
+		for _, b := range s.controls[a] {
+			buf.WriteString(" ")
+			buf.WriteString(b.String())
+		}
+		buf.WriteString("\n")
+	}
+	if s.customName != "" {
+		buf.WriteString("name ")
+		buf.WriteString(escapeField(s.customName))
+		buf.WriteString("\n")
+	}
+	if s.pack != "" {
+		buf.WriteString("pack ")
+		buf.WriteString(escapeField(s.pack))
+		buf.WriteString("\n")
+	}
+	if s.fullscreen {
+		buf.WriteString("fullscreen\n")
+	}
+	if s.accessoryCollision {
+		buf.WriteString("accessoryCollision\n")
+	}
+	buf.WriteString("language ")
+	buf.WriteString(s.language)
+	buf.WriteString("\n")
+	buf.WriteString("nameSource ")
+	buf.WriteString(s.nameSource)
+	buf.WriteString("\n")
+	for _, source := range slices.Sorted(maps.Keys(s.nameDecks)) {
+		d := s.nameDecks[source]
+		buf.WriteString("names ")
+		buf.WriteString(source)
+		buf.WriteString(" ")
+		buf.WriteString(strconv.FormatInt(d.seed, 10))
+		buf.WriteString(" ")
+		buf.WriteString(strconv.Itoa(d.position))
+		buf.WriteString(" ")
+		buf.WriteString(strconv.FormatUint(uint64(d.checksum), 10))
+		for _, name := range d.retired {
+			buf.WriteString(" ")
+			buf.WriteString(escapeField(name))
+		}
+		buf.WriteString("\n")
+		if len(d.dealt) > 0 {
+			buf.WriteString("dealt ")
+			buf.WriteString(source)
+			for _, name := range d.dealt {
+				buf.WriteString(" ")
+				buf.WriteString(escapeField(name))
+			}
+			buf.WriteString("\n")
+		}
+	}
+	for _, group := range slices.Sorted(maps.Keys(s.wardrobe)) {
+		buf.WriteString("wear ")
+		buf.WriteString(group)
+		buf.WriteString(" ")
+		buf.WriteString(s.wardrobe[group])
+		buf.WriteString("\n")
+	}
+	return buf.Bytes()
+}
+
+func bytesToSettings(data []byte) settings {
+	s := defaultSettings()
+	for line := range strings.SplitSeq(string(data), "\n") {
+		cols := strings.Fields(line)
+		if len(cols) == 0 {
+			continue
+		}
+		switch cols[0] {
+		case "bind":
+			if len(cols) < 2 {
+				continue
+			}
+			a, ok := actionFromID(cols[1])
+			if !ok {
+				continue
+			}
+			s.controls[a] = nil
+			for _, name := range cols[2:] {
+				if b, ok := parseBinding(name); ok {
+					s.controls[a] = append(s.controls[a], b)
+				}
+			}
+		case "name":
+			if len(cols) == 2 {
+				s.customName = unescapeField(cols[1])
+			}
+		case "pack":
+			if len(cols) == 2 {
+				s.pack = unescapeField(cols[1])
+			}
+		case "fullscreen":
+			s.fullscreen = true
+		case "accessoryCollision":
+			s.accessoryCollision = true
+		case "language":
+			if len(cols) == 2 {
+				s.language = cols[1]
+			}
+		case "nameSource":
+			if len(cols) == 2 {
+				s.nameSource = cols[1]
+			}
+		case "names":
+			if len(cols) < 5 {
+				continue
+			}
+			seed, err1 := strconv.ParseInt(cols[2], 10, 64)
+			position, err2 := strconv.Atoi(cols[3])
+			checksum, err3 := strconv.ParseUint(cols[4], 10, 32)
+			if err1 != nil || err2 != nil || err3 != nil || position < 0 {
+				continue
+			}
+			d := &nameDeck{
+				seed:     seed,
+				position: position,
+				checksum: uint32(checksum),
+			}
+			for _, name := range cols[5:] {
+				d.retired = append(d.retired, unescapeField(name))
+			}
+			s.nameDecks[cols[1]] = d
+		case "dealt":
+			// The dealt names follow the line of their deck.
+			if len(cols) < 2 || s.nameDecks[cols[1]] == nil {
+				continue
+			}
+			d := s.nameDecks[cols[1]]
+			d.dealt = nil
+			for _, name := range cols[2:] {
+				d.dealt = append(d.dealt, unescapeField(name))
+			}
+		case "wear":
+			if len(cols) == 3 {
+				s.wardrobe[cols[1]] = cols[2]
+			}
+		}
+	}
+	// A settings file that was edited by hand might leave the player without
+	// a way back to the settings.
+	defaults := defaultControls()
+	for a := range actionCount {
+		if a.required() && len(s.controls[a]) == 0 {
+			s.controls[a] = defaults[a]
+		}
+	}
+	return s
+}
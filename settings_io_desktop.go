@@ -0,0 +1,43 @@
+//go:build !js
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// userAccessoriesName is the player's own accessory manifest, next to the
+// history file.
+const userAccessoriesName = "flappy_go_accessories.json"
+
+func settingsPath() string {
+	return filepath.Join(historyDir(), "flappy_go_settings")
+}
+
+func saveSettings(s settings) {
+	os.WriteFile(settingsPath(), settingsToBytes(s), 0666)
+}
+
+func loadSettings() settings {
+	data, err := os.ReadFile(settingsPath())
+	if err != nil {
+		return defaultSettings()
+	}
+
+	return bytesToSettings(data)
+}
+
+// loadUserNames reads the player's own name file. It is a text file with one
+// name per line, next to the history file.
+func loadUserNames() []byte {
+	data, _ := os.ReadFile(filepath.Join(historyDir(), "flappy_go_names.txt"))
+	return data
+}
+
+// loadUserAccessories reads the player's own accessory manifest which replaces
+// rsc/accessories.json. It returns nil if there is none.
+func loadUserAccessories() []byte {
+	data, _ := os.ReadFile(filepath.Join(historyDir(), userAccessoriesName))
+	return data
+}
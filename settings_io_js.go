@@ -0,0 +1,44 @@
+//go:build js
+
+package main
+
+import "syscall/js"
+
+const (
+	settingsName        = "flappy_go_settings"
+	userNamesName       = "flappy_go_names"
+	userAccessoriesName = "flappy_go_accessories"
+)
+
+func saveSettings(s settings) {
+	text := string(settingsToBytes(s))
+	js.Global().Get("localStorage").Call("setItem", settingsName, text)
+}
+
+func loadSettings() settings {
+	item := js.Global().Get("localStorage").Call("getItem", settingsName)
+	if item.IsNull() {
+		return defaultSettings()
+	}
+	return bytesToSettings([]byte(item.String()))
+}
+
+// loadUserNames reads the player's own names from the local storage item
+// flappy_go_names, one name per line.
+func loadUserNames() []byte {
+	item := js.Global().Get("localStorage").Call("getItem", userNamesName)
+	if item.IsNull() {
+		return nil
+	}
+	return []byte(item.String())
+}
+
+// loadUserAccessories reads the player's own accessory manifest, which replaces
+// rsc/accessories.json, from the local storage item flappy_go_accessories.
+func loadUserAccessories() []byte {
+	item := js.Global().Get("localStorage").Call("getItem", userAccessoriesName)
+	if item.IsNull() {
+		return nil
+	}
+	return []byte(item.String())
+}
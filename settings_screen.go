@@ -0,0 +1,265 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// settingsOption is a setting that the player changes by clicking it, which
+// cycles through all of its possible values.
+type settingsOption struct {
+	label messageID
+	value func(s *settings) string
+	next  func(s *settings)
+}
+
+var settingsOptions = []settingsOption{
+	{
+		label: msgOptionNames,
+		value: func(s *settings) string {
+			return findNameSource(s.nameSource).title()
+		},
+		next: func(s *settings) {
+			sources := nameSources()
+			i := 0
+			for i < len(sources) && sources[i].id() != s.nameSource {
+				i++
+			}
+			s.nameSource = sources[(i+1)%len(sources)].id()
+		},
+	},
+	{
+		label: msgOptionLanguage,
+		value: func(s *settings) string {
+			lang := findLanguage(s.language)
+			if lang.missingTexts > 0 {
+				return trn(msgLanguageIncomplete, lang.missingTexts, lang.title(), lang.missingTexts)
+			}
+			return lang.title()
+		},
+		next: func(s *settings) {
+			i := slices.Index(languages, findLanguage(s.language))
+			s.language = languages[(i+1)%len(languages)].id
+			setLanguage(s.language)
+		},
+	},
+	{
+		label: msgOptionPack,
+		value: func(s *settings) string {
+			value := tr(msgNoPack)
+			if s.pack != "" {
+				value = filepath.Base(s.pack)
+			}
+			// Images and sounds that are already loaded cannot be replaced,
+			// so a different pack is only used after restarting the game.
+			if s.pack != currentPackPath {
+				value = tr(msgAfterRestart, value)
+			}
+			return value
+		},
+		next: func(s *settings) {
+			packs := append([]string{""}, availableAssetPacks()...)
+			i := slices.Index(packs, s.pack)
+			s.pack = packs[(i+1)%len(packs)]
+		},
+	},
+	{
+		label: msgAccessoryCollision,
+		value: func(s *settings) string {
+			if s.accessoryCollision {
+				return tr(msgOn)
+			}
+			return tr(msgOff)
+		},
+		next: func(s *settings) {
+			s.accessoryCollision = !s.accessoryCollision
+		},
+	},
+}
+
+// settingsScreen lets the player re-bind the actions and change the options.
+// Clicking an action (or selecting it with the arrow keys and pressing Enter)
+// waits for the next key or mouse button which is then added to or removed
+// from the action. Clicking an option cycles through its values.
+type settingsScreen struct {
+	open      bool
+	selected  int
+	capturing bool
+	// unbound is set when the player tried to take the last binding from a
+	// required action, the help text explains why that did not work.
+	unbound        *action
+	mouseX, mouseY int
+}
+
+// The rows are all actions, followed by the options, followed by the button
+// that resets the controls.
+func (s *settingsScreen) rowCount() int {
+	return int(actionCount) + len(settingsOptions) + 1
+}
+
+func (s *settingsScreen) isActionRow(row int) bool {
+	return row < int(actionCount)
+}
+
+func (s *settingsScreen) isResetRow(row int) bool {
+	return row == s.rowCount()-1
+}
+
+func (s *settingsScreen) option(row int) settingsOption {
+	return settingsOptions[row-int(actionCount)]
+}
+
+// update handles the input for the settings screen. It returns true if the
+// settings were changed and need to be saved.
+func (s *settingsScreen) update(window draw.Window, settings *settings) (changed bool) {
+	c := &settings.controls
+
+	if s.capturing {
+		a := action(s.selected)
+		if window.WasKeyPressed(draw.KeyEscape) {
+			s.capturing = false
+		} else if window.WasKeyPressed(draw.KeyBackspace) {
+			changed = c.clear(a)
+			if !changed {
+				s.unbound = &a
+			}
+			s.capturing = false
+		} else if b, ok := pressedBinding(window); ok {
+			var unbound action
+			unbound, changed = c.toggle(a, b)
+			if !changed {
+				s.unbound = &unbound
+			}
+			s.capturing = false
+		}
+		return changed
+	}
+
+	if window.WasKeyPressed(draw.KeyEscape) || c.triggered(window, actionSettings) {
+		s.open = false
+		return false
+	}
+
+	if window.WasKeyPressed(draw.KeyUp) {
+		s.selected = (s.selected + s.rowCount() - 1) % s.rowCount()
+	}
+	if window.WasKeyPressed(draw.KeyDown) {
+		s.selected = (s.selected + 1) % s.rowCount()
+	}
+
+	// Only hovering the mouse over a row selects it, so keyboard navigation
+	// still works while the mouse rests over the list.
+	mouseX, mouseY := window.MousePosition()
+	if mouseX != s.mouseX || mouseY != s.mouseY {
+		if row, ok := s.rowAt(window, mouseX, mouseY); ok {
+			s.selected = row
+		}
+	}
+	s.mouseX, s.mouseY = mouseX, mouseY
+
+	activate := window.WasKeyPressed(draw.KeyEnter) ||
+		window.WasKeyPressed(draw.KeyNumEnter)
+	for _, click := range window.Clicks() {
+		if row, ok := s.rowAt(window, click.X, click.Y); ok && click.Button == draw.LeftButton {
+			s.selected = row
+			activate = true
+		}
+	}
+
+	if activate {
+		s.unbound = nil
+		switch {
+		case s.isActionRow(s.selected):
+			s.capturing = true
+		case s.isResetRow(s.selected):
+			*c = defaultControls()
+			changed = true
+		default:
+			s.option(s.selected).next(settings)
+			changed = true
+		}
+	}
+
+	return changed
+}
+
+const (
+	settingsTitleScale = 5
+	settingsRowScale   = 2
+	settingsRowHeight  = 45
+	settingsTop        = 160
+	settingsBottom     = 100
+	settingsMargin     = 60
+)
+
+// rowRect returns the screen area of the given row. The rows are laid out in
+// columns, each column is filled from top to bottom.
+func (s *settingsScreen) rowRect(window renderer, row int) (x, y, w, h int) {
+	windowW, windowH := window.Size()
+	rowsPerColumn := max(1, (windowH-settingsTop-settingsBottom)/settingsRowHeight)
+	columnCount := (s.rowCount() + rowsPerColumn - 1) / rowsPerColumn
+	w = (windowW - 2*settingsMargin) / columnCount
+	x = settingsMargin + (row/rowsPerColumn)*w
+	y = settingsTop + (row%rowsPerColumn)*settingsRowHeight
+	return x, y, w, settingsRowHeight
+}
+
+func (s *settingsScreen) rowAt(window draw.Window, x, y int) (int, bool) {
+	for row := range s.rowCount() {
+		rowX, rowY, rowW, rowH := s.rowRect(window, row)
+		if rowX <= x && x < rowX+rowW && rowY <= y && y < rowY+rowH {
+			return row, true
+		}
+	}
+	return 0, false
+}
+
+func (s *settingsScreen) draw(window renderer, settings *settings, backgroundColor draw.Color) {
+	windowW, windowH := window.Size()
+	window.FillRect(0, 0, windowW, windowH, backgroundColor)
+
+	title := tr(msgSettings)
+	titleW, _ := window.GetScaledTextSize(title, settingsTitleScale)
+	window.DrawScaledText(title, (windowW-titleW)/2, 50, settingsTitleScale, draw.Black)
+
+	for row := range s.rowCount() {
+		x, y, w, h := s.rowRect(window, row)
+		if row == s.selected {
+			window.FillRect(x, y, w, h, draw.RGBA(1, 1, 1, 0.6))
+		}
+
+		var label, value string
+		switch {
+		case s.isActionRow(row):
+			a := action(row)
+			label = a.String()
+			value = settings.controls.describe(a)
+			if s.capturing && row == s.selected {
+				value = tr(msgPressAKey)
+			}
+		case s.isResetRow(row):
+			label = tr(msgResetControls)
+		default:
+			option := s.option(row)
+			label = tr(option.label)
+			value = option.value(settings)
+		}
+
+		_, textH := window.GetScaledTextSize(label, settingsRowScale)
+		textY := y + (h-textH)/2
+		window.DrawScaledText(label, x+20, textY, settingsRowScale, draw.Black)
+		window.DrawScaledText(value, x+w*2/5, textY, settingsRowScale, draw.RGB(0.5, 0, 0))
+	}
+
+	help := tr(msgSettingsHelp)
+	if s.capturing {
+		help = tr(msgCaptureHelp)
+	} else if s.unbound != nil {
+		help = tr(msgKeepOneBinding, *s.unbound)
+	}
+	const helpScale = 2
+	helpW, helpH := window.GetScaledTextSize(help, helpScale)
+	window.DrawScaledText(help, (windowW-helpW)/2, windowH-helpH-40, helpScale, draw.Black)
+}
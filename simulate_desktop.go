@@ -0,0 +1,182 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math/rand"
+	"slices"
+	"strings"
+	"time"
+)
+
+// runSimulate is the simulate command, see the readme. It plays many runs
+// without a window as fast as possible and reports how far the gophers got,
+// what killed them and how fast the simulation ran. The runs are the same
+// every time, so changes to the game's constants can be compared.
+func runSimulate(args []string, m *accessoryManifest) error {
+	flags := flag.NewFlagSet("simulate", flag.ContinueOnError)
+	runs := flags.Int("runs", 1000, "number of runs")
+	policyName := flags.String("policy", "follow", "who flaps: follow, random or a network `file` from flappy train")
+	seed := flags.Int64("seed", 1, "seed of the first run, the others count up from it")
+	maxSteps := flags.Int("steps", 5*60*stepsPerSecond, "a run ends after this many steps, even if the gopher is still alive")
+	gapPattern := flags.String("gaps", defaultGapPattern, "`pattern` of the gaps: "+strings.Join(gapPatternNames(), ", "))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *runs <= 0 {
+		return errors.New("runs must be positive")
+	}
+	if *maxSteps <= 0 {
+		return errors.New("steps must be positive")
+	}
+	if _, ok := gapPatterns[*gapPattern]; !ok {
+		return fmt.Errorf("unknown gap pattern %q, use one of %s", *gapPattern, strings.Join(gapPatternNames(), ", "))
+	}
+	flap, err := newPolicy(*policyName, *seed)
+	if err != nil {
+		return err
+	}
+
+	g, err := newGame(m)
+	if err != nil {
+		return err
+	}
+	// The parallax layers are only drawn and they have their own random
+	// numbers, leaving them out makes the simulation faster without changing
+	// the runs.
+	g.parallax = nil
+	g.gapPattern = *gapPattern
+
+	scores := make([]int, 0, *runs)
+	causes := make(map[string]int)
+	steps := 0
+	start := time.Now()
+	for i := range *runs {
+		g.reset(*seed+int64(i), nil)
+		for g.isAlive && g.steps < *maxSteps {
+			g.step(flap(g.observe()))
+		}
+		steps += g.steps
+		scores = append(scores, g.score)
+		causes[g.deathCause]++
+	}
+	elapsed := time.Since(start)
+
+	fmt.Printf("%d runs with the %s policy and %s gaps, seeds %d to %d\n\n", *runs, *policyName, *gapPattern, *seed, *seed+int64(*runs)-1)
+	printScores(scores)
+	fmt.Println()
+	printDeathCauses(causes, *runs)
+	fmt.Println()
+	seconds := max(elapsed.Seconds(), 1e-9)
+	fmt.Printf("%d steps in %v, %.0f steps per second, %.0f runs per second\n",
+		steps, elapsed.Round(time.Millisecond), float64(steps)/seconds, float64(*runs)/seconds)
+	return nil
+}
+
+// policy decides whether the gopher flaps.
+type policy func(obs observation) bool
+
+const (
+	// followOffset is how far below the next gap's center the follow policy
+	// lets the gopher fall before it flaps. A flap lifts the gopher by about
+	// 200 pixels.
+	followOffset = 120
+	// randomFlapChance is the chance that the random policy flaps in a step.
+	randomFlapChance = 1.0 / 50
+)
+
+// newPolicy returns the policy with the given name. Names that are no built-in
+// policy are network files from the train command.
+func newPolicy(name string, seed int64) (policy, error) {
+	switch name {
+	case "follow":
+		return followGaps, nil
+	case "random":
+		r := rand.New(rand.NewSource(seed))
+		return func(observation) bool { return r.Float64() < randomFlapChance }, nil
+	}
+	n, err := loadNetwork(name)
+	if err != nil {
+		return nil, fmt.Errorf("the policy is not follow, random or a network file: %w", err)
+	}
+	return n.flap, nil
+}
+
+// followGaps flaps whenever the gopher has fallen below the next gap's center.
+func followGaps(obs observation) bool {
+	target := float64(windowH / 2)
+	if len(obs.Gaps) > 0 {
+		target = float64(obs.Gaps[0].CenterY)
+	}
+	return obs.Y > target+followOffset
+}
+
+// scoreBuckets are the lower bounds of the histogram bars.
+var scoreBuckets = []int{0, 1, 2, 5, 10, 20, 50, 100}
+
+// printScores prints statistics and a histogram of the number of pipes in the
+// runs.
+func printScores(scores []int) {
+	sorted := slices.Clone(scores)
+	slices.Sort(sorted)
+	// percentile uses the nearest rank.
+	percentile := func(p int) int {
+		return sorted[max(0, (p*len(sorted)+99)/100-1)]
+	}
+	sum := 0
+	for _, s := range sorted {
+		sum += s
+	}
+	best := sorted[len(sorted)-1]
+	fmt.Printf("pipes: mean %.2f, median %d, 90th percentile %d, 99th percentile %d, best %d\n",
+		float64(sum)/float64(len(sorted)), percentile(50), percentile(90), percentile(99), best)
+
+	counts := make([]int, len(scoreBuckets))
+	for _, s := range sorted {
+		i, found := slices.BinarySearch(scoreBuckets, s)
+		if !found {
+			i--
+		}
+		counts[i]++
+	}
+	last := 0
+	for i, lower := range scoreBuckets {
+		if lower <= best {
+			last = i
+		}
+	}
+	const barWidth = 40
+	mostCommon := slices.Max(counts)
+	for i, count := range counts[:last+1] {
+		label := fmt.Sprint(scoreBuckets[i])
+		if i+1 == len(scoreBuckets) {
+			label += "+"
+		} else if scoreBuckets[i+1]-1 > scoreBuckets[i] {
+			label += fmt.Sprintf("-%d", scoreBuckets[i+1]-1)
+		}
+		bar := strings.Repeat("#", (count*barWidth+mostCommon-1)/mostCommon)
+		fmt.Printf("  %6s %-*s %d (%.1f%%)\n", label, barWidth, bar, count, percent(count, len(scores)))
+	}
+}
+
+// printDeathCauses prints how many gophers died of what, most common first.
+// Gophers that reached the step limit are listed as survivors.
+func printDeathCauses(causes map[string]int, runs int) {
+	fmt.Println("deaths:")
+	names := []string{deathByBottomPipe, deathByTopPipe, deathByFloor, deathByCeiling, ""}
+	slices.SortStableFunc(names, func(a, b string) int { return causes[b] - causes[a] })
+	for _, name := range names {
+		label := name
+		if name == "" {
+			label = "survived"
+		}
+		fmt.Printf("  %-11s %d (%.1f%%)\n", label, causes[name], percent(causes[name], runs))
+	}
+}
+
+func percent(count, total int) float64 {
+	return 100 * float64(count) / float64(total)
+}
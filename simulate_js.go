@@ -0,0 +1,9 @@
+//go:build js
+
+package main
+
+import "errors"
+
+func runSimulate(args []string, m *accessoryManifest) error {
+	return errors.New("the simulation is not available in the browser")
+}
@@ -0,0 +1,449 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"image"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// renderer is the part of draw.Window that draws. Everything that only draws
+// takes a renderer so it can also draw into an image, see softwareRenderer.
+type renderer interface {
+	Size() (width, height int)
+	DrawPoint(x, y int, color draw.Color)
+	DrawLine(fromX, fromY, toX, toY int, color draw.Color)
+	DrawRect(x, y, width, height int, color draw.Color)
+	FillRect(x, y, width, height int, color draw.Color)
+	DrawEllipse(x, y, width, height int, color draw.Color)
+	FillEllipse(x, y, width, height int, color draw.Color)
+	ImageSize(path string) (width, height int, err error)
+	DrawImageFile(path string, x, y int) error
+	DrawImageFileTo(path string, x, y, w, h, rotationCWDeg int) error
+	DrawImageFileRotated(path string, x, y, rotationCWDeg int) error
+	DrawImageFilePart(
+		path string,
+		sourceX, sourceY, sourceWidth, sourceHeight int,
+		destX, destY, destWidth, destHeight int,
+		rotationCWDeg int,
+	) error
+	BlurImages(blur bool)
+	GetTextSize(text string) (w, h int)
+	GetScaledTextSize(text string, scale float32) (w, h int)
+	DrawText(text string, x, y int, color draw.Color)
+	DrawScaledText(text string, x, y int, scale float32, color draw.Color)
+}
+
+// softwareFontPNG is a copy of the prototype/draw library's font.png. It has
+// 16x16 glyphs, see fontGlyphs for which ones.
+//
+//go:embed software_font.png
+var softwareFontPNG []byte
+
+const (
+	// softwareFontCellW and softwareFontCellH are the size of a glyph's cell
+	// in softwareFontPNG, including a margin of softwareFontMargin around it.
+	softwareFontCellW  = 83
+	softwareFontCellH  = 150
+	softwareFontMargin = 8
+	// Like the draw library, text of scale 1 is an eighth of the glyphs'
+	// size, slightly narrowed.
+	softwareFontScale   = 1.0 / 8
+	softwareFontKerning = 0.97
+)
+
+var softwareFont *image.NRGBA
+
+// softwareRenderer draws into an image without needing a window or a graphics
+// card. It draws like the desktop version of the draw library, only images are
+// never blurred and all edges are hard. It is meant for tests and for
+// exporting images.
+//
+// The renderer can stretch its screen to an image of a different size, see
+// newScaledSoftwareRenderer. Sizes and positions are always given in screen
+// pixels.
+type softwareRenderer struct {
+	image          *image.RGBA
+	images         map[string]*image.NRGBA
+	width, height  int
+	scaleX, scaleY float64
+}
+
+func newSoftwareRenderer(width, height int) *softwareRenderer {
+	return newScaledSoftwareRenderer(width, height, width, height)
+}
+
+// newScaledSoftwareRenderer draws a width x height screen into an image of size
+// imageW x imageH.
+func newScaledSoftwareRenderer(width, height, imageW, imageH int) *softwareRenderer {
+	return &softwareRenderer{
+		image:  image.NewRGBA(image.Rect(0, 0, imageW, imageH)),
+		images: make(map[string]*image.NRGBA),
+		width:  width,
+		height: height,
+		scaleX: float64(imageW) / float64(width),
+		scaleY: float64(imageH) / float64(height),
+	}
+}
+
+func (r *softwareRenderer) Size() (int, int) {
+	return r.width, r.height
+}
+
+// toImage converts screen to image coordinates.
+func (r *softwareRenderer) toImage(x, y int) (int, int) {
+	return round(float64(x) * r.scaleX), round(float64(y) * r.scaleY)
+}
+
+// blend draws the color over the pixel at x,y.
+func (r *softwareRenderer) blend(x, y int, c draw.Color) {
+	if !(image.Point{x, y}).In(r.image.Bounds()) || c.A <= 0 {
+		return
+	}
+	a := min(1, float64(c.A))
+	i := r.image.PixOffset(x, y)
+	p := r.image.Pix[i : i+4 : i+4]
+	mix := func(dst uint8, src float32) uint8 {
+		s := min(1, max(0, float64(src)))
+		return uint8(math.Round(float64(dst)*(1-a) + s*255*a))
+	}
+	p[0] = mix(p[0], c.R)
+	p[1] = mix(p[1], c.G)
+	p[2] = mix(p[2], c.B)
+	p[3] = uint8(math.Round(float64(p[3])*(1-a) + 255*a))
+}
+
+func (r *softwareRenderer) DrawPoint(x, y int, color draw.Color) {
+	r.FillRect(x, y, 1, 1, color)
+}
+
+func (r *softwareRenderer) DrawLine(fromX, fromY, toX, toY int, color draw.Color) {
+	fromX, fromY = r.toImage(fromX, fromY)
+	toX, toY = r.toImage(toX, toY)
+	dx, dy := abs(toX-fromX), -abs(toY-fromY)
+	stepX, stepY := 1, 1
+	if fromX > toX {
+		stepX = -1
+	}
+	if fromY > toY {
+		stepY = -1
+	}
+	err := dx + dy
+	x, y := fromX, fromY
+	for {
+		r.blend(x, y, color)
+		if x == toX && y == toY {
+			return
+		}
+		e2 := 2 * err
+		if e2 >= dy {
+			err += dy
+			x += stepX
+		}
+		if e2 <= dx {
+			err += dx
+			y += stepY
+		}
+	}
+}
+
+func (r *softwareRenderer) DrawRect(x, y, width, height int, color draw.Color) {
+	if width <= 0 || height <= 0 {
+		return
+	}
+	r.FillRect(x, y, width, 1, color)
+	if height > 1 {
+		r.FillRect(x, y+height-1, width, 1, color)
+	}
+	r.FillRect(x, y+1, 1, height-2, color)
+	if width > 1 {
+		r.FillRect(x+width-1, y+1, 1, height-2, color)
+	}
+}
+
+// toImageRect scales both corners of the rectangle, so that rectangles which
+// touch on the screen also touch in the image.
+func (r *softwareRenderer) toImageRect(x, y, width, height int) (int, int, int, int) {
+	left, top := r.toImage(x, y)
+	right, bottom := r.toImage(x+width, y+height)
+	return left, top, right - left, bottom - top
+}
+
+func (r *softwareRenderer) FillRect(x, y, width, height int, color draw.Color) {
+	x, y, width, height = r.toImageRect(x, y, width, height)
+	area := image.Rect(x, y, x+width, y+height).Intersect(r.image.Bounds())
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		for px := area.Min.X; px < area.Max.X; px++ {
+			r.blend(px, py, color)
+		}
+	}
+}
+
+// inEllipse reports whether the center of pixel x,y is in the ellipse that
+// fills the given rectangle.
+func inEllipse(x, y, left, top, width, height int) bool {
+	rx, ry := float64(width)/2, float64(height)/2
+	dx := (float64(x-left) + 0.5 - rx) / rx
+	dy := (float64(y-top) + 0.5 - ry) / ry
+	return dx*dx+dy*dy <= 1
+}
+
+func (r *softwareRenderer) DrawEllipse(x, y, width, height int, color draw.Color) {
+	x, y, width, height = r.toImageRect(x, y, width, height)
+	if width <= 0 || height <= 0 {
+		return
+	}
+	// The outline are the pixels in the ellipse with a neighbor outside.
+	for py := y; py < y+height; py++ {
+		for px := x; px < x+width; px++ {
+			if inEllipse(px, py, x, y, width, height) &&
+				(!inEllipse(px-1, py, x, y, width, height) ||
+					!inEllipse(px+1, py, x, y, width, height) ||
+					!inEllipse(px, py-1, x, y, width, height) ||
+					!inEllipse(px, py+1, x, y, width, height)) {
+				r.blend(px, py, color)
+			}
+		}
+	}
+}
+
+func (r *softwareRenderer) FillEllipse(x, y, width, height int, color draw.Color) {
+	x, y, width, height = r.toImageRect(x, y, width, height)
+	if width <= 0 || height <= 0 {
+		return
+	}
+	for py := y; py < y+height; py++ {
+		for px := x; px < x+width; px++ {
+			if inEllipse(px, py, x, y, width, height) {
+				r.blend(px, py, color)
+			}
+		}
+	}
+}
+
+// loadImage opens images like the game's window does, see openAsset.
+func (r *softwareRenderer) loadImage(path string) (*image.NRGBA, error) {
+	if img, ok := r.images[path]; ok {
+		return img, nil
+	}
+	f, err := openAsset(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	img, err := decodeNRGBA(data)
+	if err != nil {
+		return nil, err
+	}
+	r.images[path] = img
+	return img, nil
+}
+
+func decodeNRGBA(data []byte) (*image.NRGBA, error) {
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if img, ok := decoded.(*image.NRGBA); ok {
+		return img, nil
+	}
+	b := decoded.Bounds()
+	img := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := range b.Dy() {
+		for x := range b.Dx() {
+			img.Set(x, y, decoded.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return img, nil
+}
+
+func (r *softwareRenderer) ImageSize(path string) (int, int, error) {
+	img, err := r.loadImage(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	b := img.Bounds()
+	return b.Dx(), b.Dy(), nil
+}
+
+func (r *softwareRenderer) DrawImageFile(path string, x, y int) error {
+	return r.DrawImageFileRotated(path, x, y, 0)
+}
+
+func (r *softwareRenderer) DrawImageFileRotated(path string, x, y, rotationCWDeg int) error {
+	w, h, err := r.ImageSize(path)
+	if err != nil {
+		return err
+	}
+	return r.DrawImageFileTo(path, x, y, w, h, rotationCWDeg)
+}
+
+func (r *softwareRenderer) DrawImageFileTo(path string, x, y, w, h, rotationCWDeg int) error {
+	img, err := r.loadImage(path)
+	if err != nil {
+		return err
+	}
+	b := img.Bounds()
+	r.drawImage(img, b, x, y, w, h, rotationCWDeg, draw.White)
+	return nil
+}
+
+func (r *softwareRenderer) DrawImageFilePart(
+	path string,
+	sourceX, sourceY, sourceWidth, sourceHeight int,
+	destX, destY, destWidth, destHeight int,
+	rotationCWDeg int,
+) error {
+	img, err := r.loadImage(path)
+	if err != nil {
+		return err
+	}
+	source := image.Rect(sourceX, sourceY, sourceX+sourceWidth, sourceY+sourceHeight)
+	r.drawImage(img, source, destX, destY, destWidth, destHeight, rotationCWDeg, draw.White)
+	return nil
+}
+
+// drawImage draws the source rectangle of the image into the destination
+// rectangle, rotated clockwise around the destination's center and with all
+// colors multiplied by tint. A negative width or height mirrors the image, the
+// same as in the draw library.
+func (r *softwareRenderer) drawImage(img *image.NRGBA, source image.Rectangle, x, y, w, h, rotationCWDeg int, tint draw.Color) {
+	r.drawImageF(img, source, float64(x), float64(y), float64(w), float64(h), rotationCWDeg, tint)
+}
+
+func (r *softwareRenderer) drawImageF(img *image.NRGBA, source image.Rectangle, x, y, w, h float64, rotationCWDeg int, tint draw.Color) {
+	if w == 0 || h == 0 || source.Empty() {
+		return
+	}
+	centerX, centerY := x+w/2, y+h/2
+	sin, cos := math.Sincos(float64(rotationCWDeg) * math.Pi / 180)
+	halfW, halfH := math.Abs(w)/2, math.Abs(h)/2
+	extentX := halfW*math.Abs(cos) + halfH*math.Abs(sin)
+	extentY := halfW*math.Abs(sin) + halfH*math.Abs(cos)
+	area := image.Rect(
+		int(math.Floor((centerX-extentX)*r.scaleX)), int(math.Floor((centerY-extentY)*r.scaleY)),
+		int(math.Ceil((centerX+extentX)*r.scaleX)), int(math.Ceil((centerY+extentY)*r.scaleY)),
+	).Intersect(r.image.Bounds())
+
+	for py := area.Min.Y; py < area.Max.Y; py++ {
+		for px := area.Min.X; px < area.Max.X; px++ {
+			// Rotate the image pixel's center back on the screen and find it
+			// in the source.
+			dx := (float64(px)+0.5)/r.scaleX - centerX
+			dy := (float64(py)+0.5)/r.scaleY - centerY
+			u := (dx*cos+dy*sin)/w + 0.5
+			v := (-dx*sin+dy*cos)/h + 0.5
+			if u < 0 || u >= 1 || v < 0 || v >= 1 {
+				continue
+			}
+			sx := source.Min.X + int(u*float64(source.Dx()))
+			sy := source.Min.Y + int(v*float64(source.Dy()))
+			c := img.NRGBAAt(sx, sy)
+			r.blend(px, py, draw.Color{
+				R: float32(c.R) / 255 * tint.R,
+				G: float32(c.G) / 255 * tint.G,
+				B: float32(c.B) / 255 * tint.B,
+				A: float32(c.A) / 255 * tint.A,
+			})
+		}
+	}
+}
+
+// BlurImages is ignored, images are always drawn with hard edges.
+func (r *softwareRenderer) BlurImages(blur bool) {}
+
+func (r *softwareRenderer) GetTextSize(text string) (int, int) {
+	return r.GetScaledTextSize(text, 1)
+}
+
+func (r *softwareRenderer) GetScaledTextSize(text string, scale float32) (int, int) {
+	w, h := softwareGlyphSize(scale)
+	lines := strings.Split(text, "\n")
+	maxLineLength := 0
+	for _, line := range lines {
+		maxLineLength = max(maxLineLength, utf8.RuneCountInString(line))
+	}
+	return int(w*float64(maxLineLength) + 0.5), int(h*float64(len(lines)) + 0.5)
+}
+
+func softwareGlyphSize(scale float32) (w, h float64) {
+	s := float64(scale) * softwareFontScale
+	w = float64(softwareFontCellW-2*softwareFontMargin) * s * softwareFontKerning
+	h = float64(softwareFontCellH-2*softwareFontMargin) * s
+	return w, h
+}
+
+func (r *softwareRenderer) DrawText(text string, x, y int, color draw.Color) {
+	r.DrawScaledText(text, x, y, 1, color)
+}
+
+func (r *softwareRenderer) DrawScaledText(text string, x, y int, scale float32, color draw.Color) {
+	if text == "" || scale <= 0 {
+		return
+	}
+	if softwareFont == nil {
+		font, err := decodeNRGBA(softwareFontPNG)
+		if err != nil {
+			panic(err)
+		}
+		softwareFont = font
+	}
+
+	w, h := softwareGlyphSize(scale)
+	destX, destY := float64(x), float64(y)
+	for _, char := range text {
+		if char == '\n' {
+			destX = float64(x)
+			destY += h
+			continue
+		}
+		i := softwareGlyphIndex(char)
+		left := (i%16)*softwareFontCellW + softwareFontMargin
+		top := (i/16)*softwareFontCellH + softwareFontMargin
+		glyph := image.Rect(
+			left, top,
+			left+softwareFontCellW-2*softwareFontMargin, top+softwareFontCellH-2*softwareFontMargin,
+		)
+		r.drawImageF(softwareFont, glyph, destX, destY, w, h, 0, color)
+		destX += w
+	}
+}
+
+// softwareGlyphIndex returns the glyph's position in softwareFontPNG. Glyph 0
+// is empty.
+func softwareGlyphIndex(char rune) int {
+	if 32 <= char && char <= 127 {
+		return int(char)
+	}
+	if i := runeIndex(fontSymbols, char); i != -1 {
+		return 1 + i
+	}
+	if i := runeIndex(fontLetters, char); i != -1 {
+		return 128 + i
+	}
+	if i := runeIndex(fontLookalikes, char); i != -1 {
+		return int(fontLookalikesASCII[i])
+	}
+	return 0
+}
+
+// runeIndex is like strings.IndexRune but counts runes instead of bytes.
+func runeIndex(s string, char rune) int {
+	i := 0
+	for _, c := range s {
+		if c == char {
+			return i
+		}
+		i++
+	}
+	return -1
+}
@@ -0,0 +1,303 @@
+//go:build !js
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// terminalFrameSteps is the number of game steps per terminal frame. Drawing
+// every step would send too much over slow connections.
+const terminalFrameSteps = 2
+
+// runTerminal plays the game in the terminal, e.g. over SSH. The game is the
+// same as in the window, it is drawn with a softwareRenderer and every
+// character shows two of its pixels as a Unicode half block. Texts are written
+// as characters on top. Space or Enter flaps, Q, Escape or Ctrl+C quit.
+func runTerminal(settings settings, gapPattern string, m *accessoryManifest) error {
+	g, err := newGame(m)
+	if err != nil {
+		return err
+	}
+	g.gapPattern = gapPattern
+
+	restore, err := makeTerminalRaw()
+	if err != nil {
+		return err
+	}
+	defer restore()
+
+	// keys are the bytes that the terminal sends for each key press. They are
+	// read in the background so the game does not wait for them.
+	keys := make(chan []byte, 16)
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buf)
+			if err != nil {
+				close(keys)
+				return
+			}
+			keys <- slices.Clone(buf[:n])
+		}
+	}()
+
+	out := bufio.NewWriterSize(os.Stdout, 1<<16)
+	// Use the alternate screen and hide the cursor, like other full screen
+	// terminal programs.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(out, "\x1b[0m\x1b[?25h\x1b[?1049l")
+		out.Flush()
+	}()
+
+	var (
+		killHistory    []kill
+		killCount      int
+		wasRestartable bool
+		name           string
+		nameSource     string
+	)
+	restart := func() {
+		killHistory = loadKillHistory()
+		killCount = len(killHistory)
+		wasRestartable = false
+		name, nameSource = settings.customName, customNameSource
+		if name == "" {
+			name, nameSource = randomName(settings, killHistory)
+		}
+		g.reset(rand.Int63(), m.roll(statsOf(killHistory), settings.wardrobe))
+		g.accessoryCollision = settings.accessoryCollision
+		g.highscore = 0
+		for _, k := range killHistory {
+			g.highscore = max(g.highscore, k.Score)
+		}
+	}
+	restart()
+
+	screen := newTerminalScreen(os.Getenv("COLORTERM"))
+	ticker := time.NewTicker(time.Second / 60)
+	defer ticker.Stop()
+	for frame := 0; ; frame++ {
+		<-ticker.C
+
+		clicked := false
+		for more := true; more; {
+			select {
+			case key, ok := <-keys:
+				quit := !ok || string(key) == "\x1b" ||
+					slices.ContainsFunc(key, func(b byte) bool { return b == 'q' || b == 'Q' || b == 3 })
+				if quit {
+					return nil
+				}
+				// Other escape sequences are keys like the arrows, we ignore
+				// them.
+				if key[0] != '\x1b' && slices.ContainsFunc(key, func(b byte) bool {
+					return b == ' ' || b == '\r' || b == '\n'
+				}) {
+					clicked = true
+				}
+			default:
+				more = false
+			}
+		}
+
+		restartable := g.restartable()
+		if restartable != wasRestartable {
+			killCount++
+			wasRestartable = restartable
+		}
+		if restartable && clicked {
+			restart()
+			clicked = false
+		}
+
+		g.step(clicked)
+		if g.died {
+			killHistory = append(killHistory, g.kill(name, nameSource))
+			saveKillHistory(killHistory)
+		}
+
+		if frame%terminalFrameSteps != 0 {
+			continue
+		}
+
+		if frame%60 == 0 {
+			if cols, rows, err := terminalSize(); err == nil {
+				screen.resize(cols, rows)
+			}
+		}
+
+		g.draw(screen.renderer)
+		screen.clearText()
+		black := color.RGBA{0, 0, 0, 255}
+		darkRed := color.RGBA{128, 0, 0, 255}
+		screen.writeCentered(fmt.Sprintf(" %d ", g.score), 0, black)
+		highscore := " " + tr(msgHighscore, g.highscore) + " "
+		screen.write(highscore, screen.cols-utf8.RuneCountInString(highscore), 0, black)
+		status := " " + tr(msgNowPlaying, name) + " "
+		if !g.isAlive {
+			status = " " + tr(msgRecentlyDeceased, name) + " "
+		}
+		status += "- " + trn(msgDeadGophers, killCount, killCount) + " "
+		screen.write(status, screen.cols-utf8.RuneCountInString(status), screen.rows-1, darkRed)
+
+		if restartable {
+			screen.writeCentered(tr(msgPressToRestart, "Space"), 2, black)
+			screen.drawMemorial(killHistory, g.killScrollY)
+		}
+
+		screen.flush(out)
+	}
+}
+
+// terminalScreen is a grid of characters. Every character is two pixels of the
+// renderer's image, one above the other, unless it has a text on it.
+type terminalScreen struct {
+	cols, rows int
+	renderer   *softwareRenderer
+	// text has a character per cell that is written over the pixels, 0 for
+	// no text.
+	text       []rune
+	textColors []color.RGBA
+	// trueColor uses 24-bit colors, otherwise we use the 256 color palette.
+	trueColor bool
+}
+
+// newTerminalScreen creates an 80x24 screen. colorTerm is the COLORTERM
+// variable, which terminals set to announce 24-bit colors.
+func newTerminalScreen(colorTerm string) *terminalScreen {
+	s := &terminalScreen{trueColor: colorTerm == "truecolor" || colorTerm == "24bit"}
+	s.resize(80, 24)
+	return s
+}
+
+func (s *terminalScreen) resize(cols, rows int) {
+	if cols == s.cols && rows == s.rows {
+		return
+	}
+	s.cols, s.rows = cols, rows
+	s.renderer = newScaledSoftwareRenderer(windowW, windowH, cols, 2*rows)
+	s.text = make([]rune, cols*rows)
+	s.textColors = make([]color.RGBA, cols*rows)
+}
+
+func (s *terminalScreen) clearText() {
+	clear(s.text)
+}
+
+// write puts the text at the given cell, cut off at the screen's borders.
+func (s *terminalScreen) write(text string, col, row int, c color.RGBA) {
+	if row < 0 || row >= s.rows {
+		return
+	}
+	for _, r := range text {
+		if 0 <= col && col < s.cols {
+			s.text[col+row*s.cols] = r
+			s.textColors[col+row*s.cols] = c
+		}
+		col++
+	}
+}
+
+func (s *terminalScreen) writeCentered(text string, row int, c color.RGBA) {
+	s.write(text, (s.cols-utf8.RuneCountInString(text))/2, row, c)
+}
+
+// drawMemorial lists the dead gophers, newest first, in the middle of the
+// screen. The list scrolls up over time, like in the window.
+func (s *terminalScreen) drawMemorial(kills []kill, scrollY int) {
+	lines := []string{trn(msgInHonorOfOurHeroes, len(kills)), ""}
+	for i := len(kills) - 1; i >= 0; i-- {
+		lines = append(lines, eulogy(i, kills[i].Name, kills[i].Score))
+	}
+	lines = append(lines, "", tr(msgYouWillBeMissed))
+
+	const (
+		top             = 4
+		framesPerLine   = 40
+		framesUntilMove = 120
+	)
+	visible := s.rows - top - 2
+	if visible <= 0 {
+		return
+	}
+	first := max(0, min(len(lines)-visible, (-scrollY-framesUntilMove)/framesPerLine))
+	width := 0
+	for _, line := range lines {
+		width = max(width, utf8.RuneCountInString(line)+2)
+	}
+	left := (s.cols - width) / 2
+	background := strings.Repeat(" ", width)
+	for i := range min(visible, len(lines)) {
+		s.write(background, left, top+i, color.RGBA{})
+		s.writeCentered(lines[first+i], top+i, color.RGBA{128, 0, 0, 255})
+	}
+}
+
+// flush writes the screen to the terminal. Colors are only sent when they
+// change, which makes the output a lot shorter.
+func (s *terminalScreen) flush(w *bufio.Writer) {
+	pixels := s.renderer.image
+	var lastFg, lastBg color.RGBA
+	first := true
+	setColors := func(fg, bg color.RGBA) {
+		if first || fg != lastFg {
+			w.WriteString(s.color(38, fg))
+		}
+		if first || bg != lastBg {
+			w.WriteString(s.color(48, bg))
+		}
+		lastFg, lastBg, first = fg, bg, false
+	}
+
+	w.WriteString("\x1b[H")
+	for row := range s.rows {
+		if row > 0 {
+			w.WriteString("\r\n")
+		}
+		for col := range s.cols {
+			top := pixels.RGBAAt(col, 2*row)
+			bottom := pixels.RGBAAt(col, 2*row+1)
+			if r := s.text[col+row*s.cols]; r != 0 {
+				// Texts stand on a lighter background so they can be read.
+				bg := mixRGBA(mixRGBA(top, bottom), color.RGBA{255, 255, 255, 255})
+				setColors(s.textColors[col+row*s.cols], bg)
+				w.WriteRune(r)
+			} else {
+				setColors(top, bottom)
+				w.WriteString("▀")
+			}
+		}
+	}
+	w.WriteString("\x1b[0m")
+	w.Flush()
+}
+
+// color returns the escape sequence that sets the foreground (layer 38) or
+// background (layer 48) color.
+func (s *terminalScreen) color(layer int, c color.RGBA) string {
+	if s.trueColor {
+		return fmt.Sprintf("\x1b[%d;2;%d;%d;%dm", layer, c.R, c.G, c.B)
+	}
+	// Use the 6x6x6 color cube of the 256 colors.
+	cube := func(v uint8) int { return (int(v)*5 + 127) / 255 }
+	return fmt.Sprintf("\x1b[%d;5;%dm", layer, 16+36*cube(c.R)+6*cube(c.G)+cube(c.B))
+}
+
+func mixRGBA(a, b color.RGBA) color.RGBA {
+	return color.RGBA{
+		R: uint8((int(a.R) + int(b.R)) / 2),
+		G: uint8((int(a.G) + int(b.G)) / 2),
+		B: uint8((int(a.B) + int(b.B)) / 2),
+		A: 255,
+	}
+}
@@ -0,0 +1,9 @@
+//go:build js
+
+package main
+
+import "errors"
+
+func runTerminal(settings settings, gapPattern string, m *accessoryManifest) error {
+	return errors.New("the terminal mode is not available in the browser")
+}
@@ -0,0 +1,46 @@
+//go:build !js && !windows
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// makeTerminalRaw makes key presses arrive right away, without echoing them.
+// The returned function restores the terminal. We let stty do this, so we do
+// not need the system calls of every Unix.
+func makeTerminalRaw() (restore func(), err error) {
+	state, err := stty("-g")
+	if err != nil {
+		return nil, fmt.Errorf("stdin is not a terminal: %w", err)
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { stty(strings.TrimSpace(state)) }, nil
+}
+
+// terminalSize returns the number of columns and rows of the terminal.
+func terminalSize() (cols, rows int, err error) {
+	size, err := stty("size")
+	if err != nil {
+		return 0, 0, err
+	}
+	if _, err := fmt.Sscan(size, &rows, &cols); err != nil {
+		return 0, 0, err
+	}
+	if cols <= 0 || rows <= 0 {
+		return 0, 0, fmt.Errorf("invalid terminal size %dx%d", cols, rows)
+	}
+	return cols, rows, nil
+}
+
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return string(out), err
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	setConsoleMode                 = kernel32.NewProc("SetConsoleMode")
+	getConsoleScreenBufferInfoProc = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+const (
+	enableProcessedInput            = 0x0001
+	enableLineInput                 = 0x0002
+	enableEchoInput                 = 0x0004
+	enableVirtualTerminalProcessing = 0x0004
+)
+
+// makeTerminalRaw makes key presses arrive right away, without echoing them,
+// and turns on the escape sequences for colors. The returned function restores
+// the console.
+func makeTerminalRaw() (restore func(), err error) {
+	var inMode, outMode uint32
+	if err := syscall.GetConsoleMode(syscall.Stdin, &inMode); err != nil {
+		return nil, errors.New("stdin is not a console: " + err.Error())
+	}
+	if err := syscall.GetConsoleMode(syscall.Stdout, &outMode); err != nil {
+		return nil, errors.New("stdout is not a console: " + err.Error())
+	}
+	raw := inMode &^ (enableProcessedInput | enableLineInput | enableEchoInput)
+	if err := consoleMode(syscall.Stdin, raw); err != nil {
+		return nil, err
+	}
+	if err := consoleMode(syscall.Stdout, outMode|enableVirtualTerminalProcessing); err != nil {
+		consoleMode(syscall.Stdin, inMode)
+		return nil, err
+	}
+	return func() {
+		consoleMode(syscall.Stdin, inMode)
+		consoleMode(syscall.Stdout, outMode)
+	}, nil
+}
+
+func consoleMode(h syscall.Handle, mode uint32) error {
+	if ok, _, err := setConsoleMode.Call(uintptr(h), uintptr(mode)); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+// consoleScreenBufferInfo is CONSOLE_SCREEN_BUFFER_INFO, the window is the
+// visible part of the buffer.
+type consoleScreenBufferInfo struct {
+	sizeX, sizeY                           int16
+	cursorX, cursorY                       int16
+	attributes                             uint16
+	windowLeft, windowTop                  int16
+	windowRight, windowBottom              int16
+	maximumWindowSizeX, maximumWindowSizeY int16
+}
+
+// terminalSize returns the number of columns and rows of the console window.
+func terminalSize() (cols, rows int, err error) {
+	var info consoleScreenBufferInfo
+	ok, _, err := getConsoleScreenBufferInfoProc.Call(uintptr(syscall.Stdout), uintptr(unsafe.Pointer(&info)))
+	if ok == 0 {
+		return 0, 0, err
+	}
+	cols = int(info.windowRight-info.windowLeft) + 1
+	rows = int(info.windowBottom-info.windowTop) + 1
+	return cols, rows, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/fs"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// tintPrefix starts the paths of tinted images. The draw package cannot tint
+// images itself so we create tinted copies of our images when it opens them,
+// see tintedImage.
+const tintPrefix = "tint/"
+
+// tintedImage returns the path of a copy of the image with all colors
+// multiplied by the tint.
+func tintedImage(path string, tint draw.Color) string {
+	if tint.R == 1 && tint.G == 1 && tint.B == 1 {
+		return path
+	}
+	return fmt.Sprintf("%s%02x%02x%02x/%s", tintPrefix,
+		round(float64(tint.R*255)), round(float64(tint.G*255)), round(float64(tint.B*255)), path)
+}
+
+// tintedImages caches the files that openTintedImage creates.
+var (
+	tintedImages     = make(map[string][]byte)
+	tintedImagesLock sync.Mutex
+)
+
+// openTintedImage returns the image file for a path that tintedImage created.
+func openTintedImage(path string) ([]byte, error) {
+	tintedImagesLock.Lock()
+	defer tintedImagesLock.Unlock()
+	if data, ok := tintedImages[path]; ok {
+		return data, nil
+	}
+
+	hex, source, ok := strings.Cut(strings.TrimPrefix(path, tintPrefix), "/")
+	tint, err := strconv.ParseUint(hex, 16, 32)
+	if !ok || err != nil || len(hex) != 6 {
+		return nil, fmt.Errorf("invalid tinted image path %q", path)
+	}
+	r, g, b := uint32(tint>>16), uint32(tint>>8&0xFF), uint32(tint&0xFF)
+
+	data, err := fs.ReadFile(assets, source)
+	if err != nil {
+		return nil, err
+	}
+	original, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	bounds := original.Bounds()
+	tinted := image.NewNRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(original.At(x, y)).(color.NRGBA)
+			c.R = uint8(uint32(c.R) * r / 255)
+			c.G = uint8(uint32(c.G) * g / 255)
+			c.B = uint8(uint32(c.B) * b / 255)
+			tinted.SetNRGBA(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, tinted); err != nil {
+		return nil, err
+	}
+	tintedImages[path] = buf.Bytes()
+	return buf.Bytes(), nil
+}
+
+// sourceImage returns the file that an image path is loaded from. Tinted
+// images are created from other images, see tintedImage.
+func sourceImage(path string) string {
+	if rest, ok := strings.CutPrefix(path, tintPrefix); ok {
+		if _, source, ok := strings.Cut(rest, "/"); ok {
+			return source
+		}
+	}
+	return path
+}
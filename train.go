@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+)
+
+// trainer evolves networks that play the game. Every generation, the whole
+// population plays the same gaps, the fittest networks are kept and the rest
+// of the next generation are mutated children of fit networks.
+type trainer struct {
+	rand *rand.Rand
+	// networks are the population, sorted by the fitness in the last
+	// generation, so networks[0] is the best of the last generation.
+	networks []*network
+	games    []*game
+	fitness  []float64
+	// running is false for the games whose gopher died or which reached
+	// maxSteps.
+	running  []bool
+	maxSteps int
+
+	generation int
+	// best is the fittest network so far, it played bestFitness.
+	best        *network
+	bestFitness float64
+}
+
+// generationStats describe how well a generation played.
+type generationStats struct {
+	generation                              int
+	bestFitness, meanFitness, medianFitness float64
+	bestPipes                               int
+	meanPipes                               float64
+	// allTimeBest is the best fitness of all generations so far.
+	allTimeBest float64
+}
+
+const (
+	// eliteShare of each generation is copied into the next one unchanged.
+	eliteShare         = 0.1
+	tournamentSize     = 3
+	mutationChance     = 0.1
+	mutationStrength   = 0.5
+	minPopulationCount = 2
+)
+
+func newTrainer(m *accessoryManifest, population int, seed int64, maxSteps int) (*trainer, error) {
+	t := &trainer{
+		rand:        rand.New(rand.NewSource(seed)),
+		maxSteps:    maxSteps,
+		bestFitness: math.Inf(-1),
+	}
+	for range max(minPopulationCount, population) {
+		g, err := newGame(m)
+		if err != nil {
+			return nil, err
+		}
+		t.games = append(t.games, g)
+		t.networks = append(t.networks, newRandomNetwork(t.rand))
+	}
+	t.fitness = make([]float64, len(t.games))
+	t.running = make([]bool, len(t.games))
+	return t, nil
+}
+
+// start resets all games with the same new seed.
+func (t *trainer) start() {
+	seed := t.rand.Int63()
+	for i, g := range t.games {
+		g.reset(seed, g.accessories)
+		t.fitness[i] = 0
+		t.running[i] = true
+	}
+}
+
+// step advances all running games by a frame and returns false once none are
+// running anymore. Dead gophers keep falling, so they can still be drawn, but
+// their fitness is fixed when they die.
+func (t *trainer) step() bool {
+	anyRunning := false
+	for i, g := range t.games {
+		if !t.running[i] {
+			g.step(false)
+			continue
+		}
+		g.step(t.networks[i].flap(g.observe()))
+		if g.died || g.steps >= t.maxSteps {
+			t.running[i] = false
+			t.fitness[i] = fitnessOf(g)
+		} else {
+			anyRunning = true
+		}
+	}
+	return anyRunning
+}
+
+// play runs the generation to the end.
+func (t *trainer) play() {
+	t.start()
+	for t.step() {
+	}
+}
+
+// fitnessOf rewards the distance that the gopher flew. Gophers that die at
+// the same pipe are ranked by how close they were to its gap, so the first
+// generations have something to learn from.
+func fitnessOf(g *game) float64 {
+	fitness := g.x
+	if obs := g.observe(); len(obs.Gaps) > 0 {
+		fitness -= math.Abs(float64(obs.Gaps[0].CenterY) - obs.Y)
+	}
+	return fitness
+}
+
+// evolve makes the next generation from the last one's fitness and returns
+// the last generation's stats.
+func (t *trainer) evolve() generationStats {
+	order := make([]int, len(t.networks))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if t.fitness[a] > t.fitness[b] {
+			return -1
+		}
+		if t.fitness[a] < t.fitness[b] {
+			return 1
+		}
+		return 0
+	})
+
+	stats := generationStats{generation: t.generation}
+	stats.bestFitness = t.fitness[order[0]]
+	stats.medianFitness = t.fitness[order[len(order)/2]]
+	for i, g := range t.games {
+		stats.meanFitness += t.fitness[i]
+		stats.meanPipes += float64(g.score)
+		stats.bestPipes = max(stats.bestPipes, g.score)
+	}
+	stats.meanFitness /= float64(len(t.games))
+	stats.meanPipes /= float64(len(t.games))
+	if stats.bestFitness > t.bestFitness {
+		t.bestFitness = stats.bestFitness
+		t.best = &network{
+			inputs:  t.networks[order[0]].inputs,
+			hidden:  t.networks[order[0]].hidden,
+			weights: slices.Clone(t.networks[order[0]].weights),
+		}
+	}
+	stats.allTimeBest = t.bestFitness
+
+	// Pick the fitter of a few random networks as a parent.
+	parent := func() *network {
+		best := t.rand.Intn(len(t.networks))
+		for range tournamentSize - 1 {
+			i := t.rand.Intn(len(t.networks))
+			if t.fitness[i] > t.fitness[best] {
+				best = i
+			}
+		}
+		return t.networks[best]
+	}
+	elites := max(1, int(eliteShare*float64(len(t.networks))))
+	next := make([]*network, 0, len(t.networks))
+	for _, i := range order[:elites] {
+		next = append(next, t.networks[i])
+	}
+	for len(next) < len(t.networks) {
+		child := crossover(t.rand, parent(), parent())
+		child.mutate(t.rand, mutationChance, mutationStrength)
+		next = append(next, child)
+	}
+	t.networks = next
+	t.generation++
+	return stats
+}
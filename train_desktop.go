@@ -0,0 +1,163 @@
+//go:build !js
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// runTrain is the train command, see the readme. It evolves networks that play
+// the game, in a window that shows the whole population or, with -headless, as
+// fast as possible. The best network is saved after every generation that
+// found a better one, the game can load it as an opponent.
+func runTrain(args []string, m *accessoryManifest) error {
+	flags := flag.NewFlagSet("train", flag.ContinueOnError)
+	headless := flags.Bool("headless", false, "train without a window and print the stats of every generation")
+	generations := flags.Int("generations", 100, "number of generations to train with -headless, the window trains until it is closed")
+	population := flags.Int("population", 100, "number of networks in each generation")
+	seed := flags.Int64("seed", 1, "seed for the networks and the gaps, the same seed trains the same networks")
+	maxSteps := flags.Int("steps", 5*60*stepsPerSecond, "a run ends after this many steps, even if the gopher is still alive")
+	out := flags.String("out", "flappy_go_opponent.txt", "`file` for the best network")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *population < minPopulationCount {
+		return fmt.Errorf("the population must have at least %d networks", minPopulationCount)
+	}
+	if *maxSteps <= 0 {
+		return errors.New("steps must be positive")
+	}
+
+	t, err := newTrainer(m, *population, *seed, *maxSteps)
+	if err != nil {
+		return err
+	}
+
+	// finish ends the generation, prints its stats and saves the best network.
+	finish := func() error {
+		bestBefore := t.bestFitness
+		s := t.evolve()
+		fmt.Printf("generation %d: fitness best %.0f, mean %.0f, median %.0f, pipes best %d, mean %.1f\n",
+			s.generation+1, s.bestFitness, s.meanFitness, s.medianFitness, s.bestPipes, s.meanPipes)
+		if t.bestFitness > bestBefore {
+			return os.WriteFile(*out, networkToBytes(t.best), 0666)
+		}
+		return nil
+	}
+
+	if *headless {
+		for range *generations {
+			t.play()
+			if err := finish(); err != nil {
+				return err
+			}
+		}
+		fmt.Printf("saved the best network, fitness %.0f, to %s\n", t.bestFitness, *out)
+		return nil
+	}
+
+	return trainInWindow(t, m, finish)
+}
+
+// everythingUnlocked lets the trained gophers wear all accessories.
+var everythingUnlocked = playerStats{
+	bestPipes:  math.MaxInt,
+	totalPipes: math.MaxInt,
+	kills:      math.MaxInt,
+}
+
+// trainInWindow shows the population playing. The world follows the leader,
+// the first running network in the order of the last generation, which is
+// circled. Up and Down change the speed, Escape quits.
+func trainInWindow(t *trainer, m *accessoryManifest, finish func() error) error {
+	var finishErr error
+	start := func() {
+		for _, g := range t.games {
+			g.accessories = m.roll(everythingUnlocked, nil)
+		}
+		t.start()
+	}
+	start()
+
+	stepsPerFrame := 1
+	screen := newScaledWindow(windowW, windowH)
+	err := draw.RunWindow("Flappy Go Training", windowW, windowH, func(window draw.Window) {
+		screen.fit(window)
+		if screen.WasKeyPressed(draw.KeyEscape) {
+			screen.Close()
+			return
+		}
+		if screen.WasKeyPressed(draw.KeyUp) {
+			stepsPerFrame = min(64, 2*stepsPerFrame)
+		}
+		if screen.WasKeyPressed(draw.KeyDown) {
+			stepsPerFrame = max(1, stepsPerFrame/2)
+		}
+
+		for range stepsPerFrame {
+			if !t.step() {
+				if finishErr = finish(); finishErr != nil {
+					screen.Close()
+					return
+				}
+				start()
+			}
+		}
+
+		leader := 0
+		alive := 0
+		for i := range t.games {
+			if t.running[i] {
+				if alive == 0 {
+					leader = i
+				}
+				alive++
+			}
+		}
+		world := t.games[leader]
+		others := slices.Delete(slices.Clone(t.games), leader, leader+1)
+		screen.BlurImages(true)
+		world.draw(screen, others...)
+
+		c := gopherCollisionCircle(world.gopherSprite())
+		for r := c.radius + 6; r < c.radius+10; r++ {
+			screen.DrawEllipse(c.centerX-r, c.centerY-r, 2*r, 2*r, draw.RGB(1, 0.8, 0))
+		}
+
+		lines := []string{
+			fmt.Sprintf("generation %d, %d of %d alive", t.generation+1, alive, len(t.games)),
+			fmt.Sprintf("pipes %d, best fitness so far %.0f", world.score, t.bestFitness),
+			fmt.Sprintf("speed %dx (Up/Down), Escape quits", stepsPerFrame),
+		}
+		const (
+			textScale = 2
+			margin    = 8
+		)
+		w, h := 0, 0
+		for _, line := range lines {
+			lineW, lineH := screen.GetScaledTextSize(line, textScale)
+			w = max(w, lineW)
+			h += lineH
+		}
+		screen.FillRect(0, 0, w+2*margin, h+2*margin, draw.RGBA(0, 0, 0, 0.6))
+		y := margin
+		for _, line := range lines {
+			screen.DrawScaledText(line, margin, y, textScale, draw.White)
+			_, lineH := screen.GetScaledTextSize(line, textScale)
+			y += lineH
+		}
+
+		screen.drawBorders()
+	})
+	if err != nil {
+		return err
+	}
+	return finishErr
+}
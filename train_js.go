@@ -0,0 +1,9 @@
+//go:build js
+
+package main
+
+import "errors"
+
+func runTrain(args []string, m *accessoryManifest) error {
+	return errors.New("training is not available in the browser")
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// wearNothing is stored in the wardrobe for groups that the player wants to
+// leave empty.
+const wearNothing = "-"
+
+// wardrobeScreen lets the player choose what the gophers wear. For every
+// accessory group they can pick one of the unlocked accessories, nothing at
+// all, or keep it random. A preview shows a gopher in the chosen outfit.
+type wardrobeScreen struct {
+	open           bool
+	selected       int
+	mouseX, mouseY int
+	// frame animates the preview gopher.
+	frame int
+}
+
+// drawGopherFunc draws a flapping gopher wearing the given accessories,
+// centered at the given position. The frame selects the animation frame.
+type drawGopherFunc func(window renderer, centerX, centerY int, scale float64, frame int, accessories []string)
+
+// wardrobeChoices returns what the player can choose for a group: random (the
+// empty string), nothing and then all unlocked accessories of the group.
+func wardrobeChoices(m *accessoryManifest, group string, stats playerStats) []string {
+	choices := []string{"", wearNothing}
+	for _, a := range m.inGroup(group, stats, true) {
+		choices = append(choices, a.Name)
+	}
+	return choices
+}
+
+// cycle changes the choice for the group by delta steps through its choices.
+// A choice that is no longer available, e.g. because the accessory manifest
+// changed, counts as random.
+func (w *wardrobeScreen) cycle(m *accessoryManifest, stats playerStats, settings *settings, delta int) {
+	group := m.Groups[w.selected].Name
+	choices := wardrobeChoices(m, group, stats)
+	i := max(0, slices.Index(choices, settings.wardrobe[group]))
+	i = (i + delta + len(choices)) % len(choices)
+	if choices[i] == "" {
+		delete(settings.wardrobe, group)
+	} else {
+		settings.wardrobe[group] = choices[i]
+	}
+}
+
+// update handles the input for the wardrobe. It returns true if the settings
+// were changed and need to be saved.
+func (w *wardrobeScreen) update(window draw.Window, m *accessoryManifest, stats playerStats, settings *settings) (changed bool) {
+	if window.WasKeyPressed(draw.KeyEscape) || settings.controls.triggered(window, actionWardrobe) {
+		w.open = false
+		return false
+	}
+
+	rowCount := len(m.Groups)
+	if rowCount == 0 {
+		return false
+	}
+	w.selected = min(w.selected, rowCount-1)
+
+	if window.WasKeyPressed(draw.KeyUp) {
+		w.selected = (w.selected + rowCount - 1) % rowCount
+	}
+	if window.WasKeyPressed(draw.KeyDown) {
+		w.selected = (w.selected + 1) % rowCount
+	}
+
+	mouseX, mouseY := window.MousePosition()
+	if mouseX != w.mouseX || mouseY != w.mouseY {
+		if row, ok := w.rowAt(window, m, mouseX, mouseY); ok {
+			w.selected = row
+		}
+	}
+	w.mouseX, w.mouseY = mouseX, mouseY
+
+	delta := 0
+	if window.WasKeyPressed(draw.KeyRight) || window.WasKeyPressed(draw.KeyEnter) ||
+		window.WasKeyPressed(draw.KeyNumEnter) {
+		delta++
+	}
+	if window.WasKeyPressed(draw.KeyLeft) {
+		delta--
+	}
+	for _, click := range window.Clicks() {
+		if row, ok := w.rowAt(window, m, click.X, click.Y); ok {
+			w.selected = row
+			if click.Button == draw.RightButton {
+				delta--
+			} else {
+				delta++
+			}
+		}
+	}
+
+	if delta != 0 {
+		w.cycle(m, stats, settings, delta)
+		changed = true
+	}
+
+	return changed
+}
+
+// rowRect returns the screen area of the given group's row. The rows fill the
+// left half of the window, the preview is drawn in the right half.
+func (w *wardrobeScreen) rowRect(window renderer, row int) (x, y, width, height int) {
+	windowW, _ := window.Size()
+	return settingsMargin, settingsTop + row*settingsRowHeight, windowW/2 - settingsMargin, settingsRowHeight
+}
+
+func (w *wardrobeScreen) rowAt(window draw.Window, m *accessoryManifest, x, y int) (int, bool) {
+	for row := range m.Groups {
+		rowX, rowY, rowW, rowH := w.rowRect(window, row)
+		if rowX <= x && x < rowX+rowW && rowY <= y && y < rowY+rowH {
+			return row, true
+		}
+	}
+	return 0, false
+}
+
+func (w *wardrobeScreen) draw(
+	window renderer,
+	m *accessoryManifest,
+	stats playerStats,
+	settings *settings,
+	backgroundColor draw.Color,
+	drawGopher drawGopherFunc,
+) {
+	w.frame++
+
+	windowW, windowH := window.Size()
+	window.FillRect(0, 0, windowW, windowH, backgroundColor)
+
+	title := tr(msgWardrobe)
+	titleW, _ := window.GetScaledTextSize(title, settingsTitleScale)
+	window.DrawScaledText(title, (windowW-titleW)/2, 50, settingsTitleScale, draw.Black)
+
+	// Random groups show a different accessory every second in the preview.
+	const randomPreviewFrames = 60
+	var preview []string
+
+	for row, g := range m.Groups {
+		x, y, rowW, rowH := w.rowRect(window, row)
+		if row == w.selected {
+			window.FillRect(x, y, rowW, rowH, draw.RGBA(1, 1, 1, 0.6))
+		}
+
+		choice := settings.wardrobe[g.Name]
+		choices := wardrobeChoices(m, g.Name, stats)
+		if !slices.Contains(choices, choice) {
+			choice = ""
+		}
+
+		var value string
+		switch choice {
+		case "":
+			value = tr(msgWearRandom)
+			random := choices[1:]
+			choice = random[(w.frame/randomPreviewFrames+row)%len(random)]
+		case wearNothing:
+			value = tr(msgWearNothing)
+		default:
+			a, _ := m.find(choice)
+			value = a.title()
+		}
+		if choice != wearNothing {
+			preview = append(preview, choice)
+		}
+
+		label := g.title()
+		_, textH := window.GetScaledTextSize(label, settingsRowScale)
+		textY := y + (rowH-textH)/2
+		window.DrawScaledText(label, x+20, textY, settingsRowScale, draw.Black)
+		window.DrawScaledText(value, x+rowW*2/5, textY, settingsRowScale, draw.RGB(0.5, 0, 0))
+	}
+
+	// List what the player still has to do to unlock more accessories for the
+	// selected group.
+	if w.selected < len(m.Groups) {
+		_, y, _, _ := w.rowRect(window, len(m.Groups))
+		y += 20
+		for _, a := range m.inGroup(m.Groups[w.selected].Name, stats, false) {
+			text := tr(msgLocked, a.title(), a.Unlock)
+			_, textH := window.GetScaledTextSize(text, settingsRowScale)
+			window.DrawScaledText(text, settingsMargin+20, y, settingsRowScale, draw.RGB(0.3, 0.3, 0.3))
+			y += textH + 5
+		}
+	}
+
+	const previewScale = 2
+	drawGopher(window, windowW*3/4, windowH/2, previewScale, w.frame, preview)
+
+	help := tr(msgWardrobeHelp)
+	const helpScale = 2
+	helpW, helpH := window.GetScaledTextSize(help, helpScale)
+	window.DrawScaledText(help, (windowW-helpW)/2, windowH-helpH-40, helpScale, draw.Black)
+}
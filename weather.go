@@ -0,0 +1,302 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+
+	"github.com/gonutz/prototype/draw"
+)
+
+// weather is picked at random for every run. Rain and snow are only for the
+// looks but wind blows the gopher up and down.
+type weather struct {
+	// id is stored in the kill history.
+	id     string
+	title  messageID
+	rain   bool
+	snow   bool
+	wind   bool
+	weight float64
+	// color marks runs with this weather in the statistics.
+	color draw.Color
+}
+
+var weathers = []weather{
+	{id: "clear", title: msgWeatherClear, weight: 4, color: draw.RGBA(0, 0, 0, 0.9)},
+	{id: "rain", title: msgWeatherRain, rain: true, weight: 2, color: draw.RGBA(0.1, 0.3, 0.8, 0.9)},
+	{id: "snow", title: msgWeatherSnow, snow: true, weight: 1, color: draw.RGBA(0.4, 0.6, 0.7, 0.9)},
+	{id: "storm", title: msgWeatherStorm, rain: true, wind: true, weight: 1, color: draw.RGBA(0.5, 0.1, 0.6, 0.9)},
+	{id: "blizzard", title: msgWeatherBlizzard, snow: true, wind: true, weight: 1, color: draw.RGBA(0.1, 0.6, 0.6, 0.9)},
+}
+
+func findWeather(id string) (*weather, bool) {
+	for i := range weathers {
+		if weathers[i].id == id {
+			return &weathers[i], true
+		}
+	}
+	return nil, false
+}
+
+const (
+	rainDropCount  = 300
+	snowFlakeCount = 150
+	// weatherSpeed is how fast rain and snow move with the gopher, relative
+	// to its speed. They are between the clouds and the city.
+	weatherSpeed = 0.3
+	// Gusts push the gopher up or down with a force of up to maxGustForce,
+	// compared to a gravity of 0.5.
+	minGustForce  = 0.1
+	maxGustForce  = 0.3
+	minGustFrames = 60
+	maxGustFrames = 150
+	minCalmFrames = 120
+	maxCalmFrames = 360
+)
+
+// weatherState is the weather of the current run. Everything random about it
+// comes from the run's seed so a run can be replayed exactly.
+type weatherState struct {
+	kind *weather
+	// gusts influence the game, particleRand only decides the looks. step
+	// counts the updates.
+	gusts        *gusts
+	step         int
+	particleRand *rand.Rand
+	particles    []weatherParticle
+}
+
+type weatherParticle struct {
+	x, y   float64
+	ySpeed float64
+	size   int
+}
+
+func newWeatherState(seed int64) *weatherState {
+	gustRand := rand.New(rand.NewSource(seed))
+	w := &weatherState{particleRand: rand.New(rand.NewSource(seed + 1))}
+
+	var totalWeight float64
+	for _, kind := range weathers {
+		totalWeight += kind.weight
+	}
+	r := gustRand.Float64() * totalWeight
+	w.kind = &weathers[len(weathers)-1]
+	for i := range weathers {
+		if r < weathers[i].weight {
+			w.kind = &weathers[i]
+			break
+		}
+		r -= weathers[i].weight
+	}
+
+	w.gusts = newGusts(gustRand, w.kind.wind)
+	return w
+}
+
+// gust is the wind in one step. A gust lasts frames frames and blows with up
+// to maxForce, negative forces blow upwards. Between gusts, maxForce is 0 and
+// frames counts the calm frames.
+type gust struct {
+	frame, frames int
+	maxForce      float64
+}
+
+// force is the vertical force of the gust in its current frame.
+func (g gust) force() float64 {
+	if g.maxForce == 0 {
+		return 0
+	}
+	// Gusts grow and fade smoothly.
+	t := float64(g.frame) / float64(g.frames)
+	return g.maxForce * math.Sin(t*math.Pi)
+}
+
+// gusts are the wind of a run. They do not depend on what the gopher does, so
+// the gusts of later steps are known in advance, see solvableGaps.
+type gusts struct {
+	rand *rand.Rand
+	wind bool
+	// steps are the gusts from step first on, next is the one after them.
+	steps []gust
+	first int
+	next  gust
+}
+
+func newGusts(r *rand.Rand, wind bool) *gusts {
+	g := &gusts{rand: r, wind: wind}
+	g.next.frames = g.randomFrames(minCalmFrames, maxCalmFrames)
+	return g
+}
+
+func (g *gusts) randomFrames(low, high int) int {
+	return low + g.rand.Intn(high-low+1)
+}
+
+// at returns the gust in the step, which must not have been forgotten.
+func (g *gusts) at(step int) gust {
+	for g.first+len(g.steps) <= step {
+		g.steps = append(g.steps, g.next)
+		g.next = g.after(g.next)
+	}
+	return g.steps[step-g.first]
+}
+
+// forget drops the gusts before the step.
+func (g *gusts) forget(step int) {
+	n := min(step-g.first, len(g.steps))
+	g.steps = g.steps[n:]
+	g.first += n
+}
+
+// calm makes the wind calm from the step on, where solvableGaps finds no way
+// through the gusts.
+func (g *gusts) calm(step int) {
+	g.at(step)
+	g.steps = g.steps[:step-g.first]
+	g.next = gust{frames: g.randomFrames(minCalmFrames, maxCalmFrames)}
+}
+
+// after returns the gust in the step after the given one.
+func (g *gusts) after(current gust) gust {
+	if !g.wind {
+		return current
+	}
+	next := current
+	next.frame++
+	if next.frame >= next.frames {
+		next.frame = 0
+		if next.maxForce == 0 {
+			next.frames = g.randomFrames(minGustFrames, maxGustFrames)
+			next.maxForce = minGustForce + g.rand.Float64()*(maxGustForce-minGustForce)
+			if g.rand.Intn(2) == 0 {
+				next.maxForce = -next.maxForce
+			}
+		} else {
+			next.frames = g.randomFrames(minCalmFrames, maxCalmFrames)
+			next.maxForce = 0
+		}
+	}
+	return next
+}
+
+// gust is the wind in the current step.
+func (w *weatherState) gust() gust {
+	return w.gusts.at(w.step)
+}
+
+// force is the vertical force of the current gust, if any.
+func (w *weatherState) force() float64 {
+	return w.gust().force()
+}
+
+// update advances the weather by one frame. The rain and snow fill the given
+// area and move with the gopher's xSpeed.
+func (w *weatherState) update(windowW, windowH int, xSpeed float64) {
+	w.step++
+	w.gusts.forget(w.step)
+
+	count := 0
+	if w.kind.rain {
+		count = rainDropCount
+	}
+	if w.kind.snow {
+		count = snowFlakeCount
+	}
+	r := w.particleRand
+	for len(w.particles) < count {
+		w.particles = append(w.particles, w.newParticle(r.Float64()*float64(windowW), r.Float64()*float64(windowH)))
+	}
+
+	// Gusts make rain and snow fall faster or slower, which shows the player
+	// where the wind blows.
+	gust := w.force() * 20
+	for i := range w.particles {
+		p := &w.particles[i]
+		p.x -= xSpeed * weatherSpeed
+		p.y += p.ySpeed + gust
+		if w.kind.snow {
+			p.x += math.Sin(p.y*0.02) * 0.5
+		}
+		if p.x < -10 {
+			p.x += float64(windowW) + 20
+		}
+		if p.x > float64(windowW)+10 {
+			p.x -= float64(windowW) + 20
+		}
+		if p.y > float64(windowH) {
+			*p = w.newParticle(r.Float64()*float64(windowW), -10)
+		}
+		if p.y < -20 {
+			*p = w.newParticle(r.Float64()*float64(windowW), float64(windowH))
+		}
+	}
+}
+
+func (w *weatherState) newParticle(x, y float64) weatherParticle {
+	r := w.particleRand
+	if w.kind.snow {
+		return weatherParticle{x: x, y: y, ySpeed: 1 + r.Float64()*1.5, size: 3 + r.Intn(4)}
+	}
+	return weatherParticle{x: x, y: y, ySpeed: 14 + r.Float64()*6, size: 12 + r.Intn(10)}
+}
+
+func (w *weatherState) draw(window renderer) {
+	for _, p := range w.particles {
+		x, y := round(p.x), round(p.y)
+		if w.kind.snow {
+			window.FillEllipse(x, y, p.size, p.size, draw.RGBA(1, 1, 1, 0.9))
+		} else {
+			window.DrawLine(x, y, x-2, y+p.size, draw.RGBA(0.3, 0.4, 0.7, 0.6))
+		}
+	}
+}
+
+// weather returns the weather of the run. Runs from before there was weather
+// had a clear sky.
+func (k kill) weather() *weather {
+	if w, ok := findWeather(k.Weather); ok {
+		return w
+	}
+	return &weathers[0]
+}
+
+// drawWeatherLegend lists how many runs the player had in each weather and
+// their best score in it, in the colors of the statistics bars. The entries
+// wrap into new lines to fit into the given width. It returns the y below the
+// legend.
+func drawWeatherLegend(window renderer, kills []kill, x, y, width int) int {
+	const (
+		textScale = 1.2
+		boxSize   = 10
+		spacing   = 25
+	)
+	lineX := x
+	lineH := 0
+	for i := range weathers {
+		w := &weathers[i]
+		runs, best := 0, 0
+		for _, k := range kills {
+			if k.weather() == w {
+				runs++
+				best = max(best, k.Score)
+			}
+		}
+		if runs == 0 {
+			continue
+		}
+
+		text := trn(msgWeatherRuns, runs, tr(w.title), runs, best)
+		textW, textH := window.GetScaledTextSize(text, textScale)
+		entryW := boxSize + 5 + textW
+		if lineX > x && lineX+entryW > x+width {
+			lineX = x
+			y += textH + 5
+		}
+		window.FillRect(lineX, y+(textH-boxSize)/2, boxSize, boxSize, w.color)
+		window.DrawScaledText(text, lineX+boxSize+5, y, textScale, draw.RGBA(0, 0, 0, 0.9))
+		lineX += entryW + spacing
+		lineH = textH + 5
+	}
+	return y + lineH
+}